@@ -0,0 +1,440 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+
+	. "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ------------------------------------------------------------------------------------
+// Autopilot
+// ------------------------------------------------------------------------------------
+//
+// The autopilot steers the snake of the first player ([TAB] turns it on and off). Right before every move it looks
+// for the shortest path to a fruit with A*, through the edges in wrap-around mode and through the portals.
+// The squares of the snakes block the way, except the ones the tail will have left by the time the head gets there.
+//
+// Eating a fruit is only worth it if the snake doesn't trap itself doing so: the autopilot plays the path ahead,
+// and checks that the head can still reach the end of the tail from there. If it can't, or if there is no path
+// to any fruit, it falls back to (in this order):
+//
+//  1. the Hamiltonian cycle of the board, a closed path going through every square once: following it, the snake
+//     can never bite itself. It's only built for the open field, a board without walls nor portals
+//     (see hamiltonianNext): on the other levels, this fallback is skipped.
+//  2. following its own tail, waiting for a better time to go for a fruit.
+//  3. the square with the most room around it.
+//
+// Without the cycle, the snake may end up following its tail forever, with the fruits out of safe reach: a headless
+// game is stopped when that happens (see autopilotPatience).
+//
+// The -headless flag lets the autopilot play games without opening a window, one random seed per game, and prints
+// how long the snake got on average.
+
+// autopilotPatience is the number of frames without eating a fruit after which a headless game is stopped:
+// the autopilot is going round in circles.
+const autopilotPatience = 60 * gridColumns * gridRows
+
+// directions are the four ways a snake can head to, in squares.
+var directions = [...]square{{1, 0}, {0, 1}, {-1, 0}, {0, -1}}
+
+// freeTimes tells, for every square of the board, in how many moves it's free for the head of a snake to step on.
+// 0 is free right away, and blocked is never (as far as the autopilot knows).
+type freeTimes [gridColumns][gridRows]int
+
+const blocked = math.MaxInt32
+
+// pathNode is a square reached by the A* search, and how far it is from the start (moves) and from a goal (estimate).
+type pathNode struct {
+	at       square
+	moves    int
+	estimate int
+}
+
+// pathQueue is the priority queue of the A* search (see container/heap): the node closest to a goal comes first.
+type pathQueue []pathNode
+
+func (q pathQueue) Len() int { return len(q) }
+func (q pathQueue) Less(i, j int) bool {
+	return q[i].moves+q[i].estimate < q[j].moves+q[j].estimate
+}
+func (q pathQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(pathNode)) }
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	node := old[len(old)-1]
+	*q = old[:len(old)-1]
+
+	return node
+}
+
+// updateAutopilot turns the autopilot on and off with [TAB].
+func updateAutopilot() {
+	if IsKeyPressed(KeyTab) {
+		isAutopilot = !isAutopilot
+	}
+
+	if isAutopilot {
+		hasAutopilotPlayed = true
+	}
+}
+
+// steer chooses where the snake heads to on its next move. It plans on the frame the snake moves, so that
+// the plan is made with the fruits and the snakes as they are.
+func (s *Snake) steer() {
+	if s.moveCounter+1 < s.framesPerMove() {
+		return
+	}
+
+	head := squareOf(s.body.at(0))
+	free := s.freeTimes()
+
+	// The snake can't turn back (see queueTurn): the square behind the head is out of reach on the next move
+	back := square{x: -int(s.speed.X) / SQUARE_SIZE, y: -int(s.speed.Y) / SQUARE_SIZE}
+	if behind, ok := step(head, back); ok && back != (square{}) && free[behind.x][behind.y] < 2 {
+		free[behind.x][behind.y] = 2
+	}
+
+	autopilotPath = findPath(head, &free, isFruitSquare, fruitDistance)
+	if autopilotPath == nil || !s.canReachTailAfter(autopilotPath) {
+		autopilotPath = s.fallbackPath(head, &free)
+	}
+
+	if len(autopilotPath) == 0 {
+		return // Nowhere to go: keep going straight
+	}
+
+	// The autopilot drops the turns queued by the player, and turns like them (it can't turn back either)
+	s.turnQueue = s.turnQueue[:0]
+	for _, d := range directions {
+		if next, ok := step(head, d); ok && next == autopilotPath[0] {
+			s.queueTurn(Vector2{X: float32(d.x * SQUARE_SIZE), Y: float32(d.y * SQUARE_SIZE)})
+			return
+		}
+	}
+}
+
+// fallbackPath returns the first moves of the snake when going for a fruit isn't safe (see the fallbacks above).
+func (s *Snake) fallbackPath(head square, free *freeTimes) []square {
+	if next, ok := hamiltonianNext(head); ok && free[next.x][next.y] <= 1 {
+		return []square{next}
+	}
+
+	tail := squareOf(s.body.at(s.body.len() - 1))
+	if path := findPath(head, free, func(q square) bool { return q == tail }, noEstimate); path != nil {
+		return path
+	}
+
+	var best []square
+	bestRoom := 0
+	for _, d := range directions {
+		next, ok := step(head, d)
+		if !ok || free[next.x][next.y] > 1 {
+			continue
+		}
+
+		if room := countRoom(next, free); room > bestRoom {
+			best, bestRoom = []square{next}, room
+		}
+	}
+
+	return best
+}
+
+// freeTimes returns when every square of the board is free for the head of the snake: the squares of its own body
+// are left one after the other as it moves, the walls and the other snakes are in the way for good.
+func (s *Snake) freeTimes() freeTimes {
+	body := make([]square, s.body.len())
+	for i := range body {
+		body[i] = squareOf(s.body.at(i))
+	}
+
+	return s.freeTimesWith(body)
+}
+
+// freeTimesWith is freeTimes with the body of the snake on the given squares (from the head to the end of the tail).
+func (s *Snake) freeTimesWith(body []square) freeTimes {
+	var free freeTimes
+
+	for x := 0; x < gridColumns; x++ {
+		for y := 0; y < gridRows; y++ {
+			if board[x][y] == tileWall {
+				free[x][y] = blocked
+			}
+		}
+	}
+
+	for k := range snakes {
+		if other := &snakes[k]; other != s && other.isAlive {
+			for i := 0; i < other.body.len(); i++ {
+				q := squareOf(other.body.at(i))
+				free[q.x][q.y] = blocked
+			}
+		}
+	}
+
+	// The end of the tail leaves on the next move, the segment before it on the one after, and so on
+	for i, q := range body {
+		if moves := len(body) - i; moves > free[q.x][q.y] {
+			free[q.x][q.y] = moves
+		}
+	}
+
+	return free
+}
+
+// canReachTailAfter reports whether the snake can still reach the end of its tail once it has followed the path,
+// and eaten the fruit at its end: it hasn't trapped itself.
+func (s *Snake) canReachTailAfter(path []square) bool {
+	length := s.body.len() + 1 // The fruit makes it grow
+
+	body := make([]square, 0, len(path)+s.body.len())
+	for i := len(path) - 1; i >= 0; i-- {
+		body = append(body, path[i])
+	}
+	for i := 0; i < s.body.len(); i++ {
+		body = append(body, squareOf(s.body.at(i)))
+	}
+
+	if length > len(body) {
+		length = len(body)
+	}
+	body = body[:length]
+
+	free := s.freeTimesWith(body)
+	tail := body[length-1]
+
+	return findPath(body[0], &free, func(q square) bool { return q == tail }, noEstimate) != nil
+}
+
+// findPath looks for the shortest path from a square to any square that is a goal, with A*: the estimate is
+// a lower bound of the moves left to reach a goal. It returns the squares of the path after the start one,
+// or nil if there is no path.
+func findPath(start square, free *freeTimes, isGoal func(square) bool, estimate func(square) int) []square {
+	var (
+		from  [gridColumns][gridRows]square
+		moves [gridColumns][gridRows]int
+	)
+
+	for x := range moves {
+		for y := range moves[x] {
+			moves[x][y] = -1
+		}
+	}
+	moves[start.x][start.y] = 0
+
+	queue := &pathQueue{{at: start, estimate: estimate(start)}}
+	for queue.Len() > 0 {
+		node := heap.Pop(queue).(pathNode)
+		if node.moves > moves[node.at.x][node.at.y] {
+			continue // Reached again, through a shorter way
+		}
+
+		if node.at != start && isGoal(node.at) {
+			// Walk the path back to the start
+			path := make([]square, node.moves)
+			for q, k := node.at, node.moves-1; k >= 0; q, k = from[q.x][q.y], k-1 {
+				path[k] = q
+			}
+
+			return path
+		}
+
+		for _, d := range directions {
+			next, ok := step(node.at, d)
+			if !ok || free[next.x][next.y] > node.moves+1 {
+				continue
+			}
+
+			if m := moves[next.x][next.y]; m >= 0 && m <= node.moves+1 {
+				continue
+			}
+
+			moves[next.x][next.y] = node.moves + 1
+			from[next.x][next.y] = node.at
+			heap.Push(queue, pathNode{at: next, moves: node.moves + 1, estimate: estimate(next)})
+		}
+	}
+
+	return nil
+}
+
+// step returns the square the head of a snake gets to from a square, heading to the given direction: through
+// the edges in wrap-around mode and through the portals (see wrap.go). It returns false if the head would
+// leave the board or bump into a wall.
+func step(from, direction square) (square, bool) {
+	position := squarePosition(from.x+direction.x, from.y+direction.y)
+	if isWrapAround {
+		position = wrapPosition(position)
+	}
+
+	x, y, ok := positionSquare(teleport(position))
+	if !ok || board[x][y] == tileWall {
+		return square{}, false
+	}
+
+	return square{x: x, y: y}, true
+}
+
+// squareOf returns the square of the board at the given position (in pixels).
+func squareOf(position Vector2) square {
+	x, y, _ := positionSquare(position)
+
+	return square{x: x, y: y}
+}
+
+// isFruitSquare reports whether there is a fruit on the square.
+func isFruitSquare(q square) bool {
+	return hasFruit(q.x, q.y)
+}
+
+// fruitDistance returns the number of moves to the closest fruit, if nothing is in the way. The portals make
+// every fruit close, so there is no estimate on a level with portals.
+func fruitDistance(q square) int {
+	if len(currentLevel().portals) > 0 {
+		return 0
+	}
+
+	closest := gridColumns + gridRows
+	for _, f := range fruits {
+		if !f.active {
+			continue
+		}
+
+		target := squareOf(f.position)
+		dx, dy := abs(target.x-q.x), abs(target.y-q.y)
+		if isWrapAround {
+			if gridColumns-dx < dx {
+				dx = gridColumns - dx
+			}
+			if gridRows-dy < dy {
+				dy = gridRows - dy
+			}
+		}
+
+		if dx+dy < closest {
+			closest = dx + dy
+		}
+	}
+
+	return closest
+}
+
+// noEstimate is the estimate of a search with no better guess than 0 (a breadth-first search).
+func noEstimate(square) int {
+	return 0
+}
+
+// countRoom returns the number of squares the head can reach from a square: the room the snake would have there.
+func countRoom(from square, free *freeTimes) int {
+	var visited [gridColumns][gridRows]bool
+	visited[from.x][from.y] = true
+
+	queue := []square{from}
+	for k := 0; k < len(queue); k++ {
+		for _, d := range directions {
+			next, ok := step(queue[k], d)
+			if !ok || visited[next.x][next.y] || free[next.x][next.y] > 1 {
+				continue
+			}
+
+			visited[next.x][next.y] = true
+			queue = append(queue, next)
+		}
+	}
+
+	return len(queue)
+}
+
+// hamiltonianNext returns the square after the given one on the Hamiltonian cycle of the board, and whether
+// the board has one. The cycle goes right and left along the rows (leaving the first column out), row after row,
+// and back to the top along the first column:
+//
+//	┌─────────┐
+//	│ ┌───────┘
+//	│ └───────┐
+//	└─────────┘
+//
+// NOTE: It needs an even number of rows, and a board without walls nor portals.
+func hamiltonianNext(q square) (square, bool) {
+	if gridRows%2 != 0 {
+		return q, false
+	}
+
+	for x := 0; x < gridColumns; x++ {
+		for y := 0; y < gridRows; y++ {
+			if board[x][y] != tileFloor {
+				return q, false
+			}
+		}
+	}
+
+	switch {
+	case q.x == 0 && q.y == 0:
+		return square{x: 1, y: 0}, true
+	case q.x == 0:
+		return square{x: 0, y: q.y - 1}, true // Back to the top
+	case q.y%2 == 0 && q.x < gridColumns-1:
+		return square{x: q.x + 1, y: q.y}, true
+	case q.y%2 == 0:
+		return square{x: q.x, y: q.y + 1}, true
+	case q.x > 1:
+		return square{x: q.x - 1, y: q.y}, true
+	case q.y < gridRows-1:
+		return square{x: 1, y: q.y + 1}, true
+	default:
+		return square{x: 0, y: q.y}, true // The last row leads to the first column
+	}
+}
+
+// drawAutopilotPath draws the path the autopilot has planned, as small squares.
+func drawAutopilotPath() {
+	size := Vector2{X: float32(SQUARE_SIZE) / 3, Y: float32(SQUARE_SIZE) / 3}
+
+	for _, q := range autopilotPath {
+		position := squarePosition(q.x, q.y)
+		position.X += size.X
+		position.Y += size.Y
+
+		DrawRectangleV(position, size, Fade(Orange, 0.6))
+	}
+}
+
+// runHeadless lets the autopilot play the given number of single player games without opening a window,
+// one random seed (1, 2, ...) per game, and prints how long the snake got.
+func runHeadless(games int) {
+	isAutopilot = true
+	totalLength := 0
+
+	for seed := 1; seed <= games; seed++ {
+		SetRandomSeed(uint32(seed))
+
+		InitGame()
+		isSelectingLevel = false
+
+		lastMeal, eaten := 0, 0
+		for !gameOver && framesCounter-lastMeal < autopilotPatience {
+			UpdateGame()
+
+			if fruitsEaten != eaten {
+				lastMeal, eaten = framesCounter, fruitsEaten
+			}
+		}
+
+		snake := &snakes[0]
+		totalLength += snake.body.len()
+
+		result := "dead"
+		switch {
+		case isVictory:
+			result = "filled the board"
+		case !gameOver:
+			result = "going round in circles"
+		}
+
+		fmt.Printf("seed %d: length %d, %d points, level %d (%s)\n", seed, snake.body.len(), snake.score, level, result)
+	}
+
+	fmt.Printf("\n%d games: %.2f squares long on average\n", games, float64(totalLength)/float64(games))
+}
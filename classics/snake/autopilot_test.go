@@ -0,0 +1,104 @@
+package main
+
+import "testing"
+
+// isNextTo reports whether two squares of the board are side by side.
+func isNextTo(a, b square) bool {
+	return abs(a.x-b.x)+abs(a.y-b.y) == 1
+}
+
+func TestFindPath(t *testing.T) {
+	useEmptyBoard(t)
+
+	savedWrapAround := isWrapAround
+	defer func() { isWrapAround = savedWrapAround }()
+
+	goal := square{5, 2}
+	toGoal := func(q square) int { return abs(goal.x-q.x) + abs(goal.y-q.y) }
+
+	var wall freeTimes
+	for y := 1; y <= 3; y++ {
+		wall[4][y] = blocked
+	}
+
+	var walledIn freeTimes
+	for _, d := range directions {
+		walledIn[goal.x+d.x][goal.y+d.y] = blocked
+	}
+
+	tests := []struct {
+		name       string
+		start      square
+		free       freeTimes
+		wrapAround bool
+		want       int // Length of the path, -1 if there is none
+	}{
+		{"straight", square{2, 2}, freeTimes{}, false, 3},
+		{"around a wall", square{2, 2}, wall, false, 7},
+		{"tail left in time", square{2, 2}, freeTimes{3: {2: 1}}, false, 3},
+		{"tail still there", square{2, 2}, freeTimes{3: {2: 5}}, false, 5},
+		{"walled in", square{2, 2}, walledIn, false, -1},
+		{"through the edge", square{gridColumns - 1, 2}, freeTimes{}, true, 6},
+		{"not through the edge", square{gridColumns - 1, 2}, freeTimes{}, false, gridColumns - 1 - goal.x},
+	}
+
+	for _, tt := range tests {
+		isWrapAround = tt.wrapAround
+
+		path := findPath(tt.start, &tt.free, func(q square) bool { return q == goal }, toGoal)
+		if tt.want < 0 {
+			if path != nil {
+				t.Errorf("%s: found the path %v", tt.name, path)
+			}
+
+			continue
+		}
+
+		if len(path) != tt.want || path[len(path)-1] != goal {
+			t.Errorf("%s: path %v, want %d moves to %v", tt.name, path, tt.want, goal)
+			continue
+		}
+
+		previous := tt.start
+		for k, q := range path {
+			isStep := isNextTo(previous, q) || (tt.wrapAround && previous.y == q.y && abs(previous.x-q.x) == gridColumns-1)
+			if !isStep || tt.free[q.x][q.y] > k+1 {
+				t.Errorf("%s: move %d to %v is not allowed (path %v)", tt.name, k+1, q, path)
+				break
+			}
+
+			previous = q
+		}
+	}
+}
+
+// Following the Hamiltonian cycle from any square goes through every square of the board, and back.
+func TestHamiltonianNext(t *testing.T) {
+	useEmptyBoard(t)
+
+	var visited [gridColumns][gridRows]bool
+
+	q := square{0, 0}
+	for k := 0; k < gridColumns*gridRows; k++ {
+		next, ok := hamiltonianNext(q)
+		if !ok {
+			t.Fatal("the open field has no Hamiltonian cycle")
+		}
+
+		if !isNextTo(q, next) || visited[next.x][next.y] {
+			t.Fatalf("move %d: from %v to %v", k+1, q, next)
+		}
+
+		visited[next.x][next.y] = true
+		q = next
+	}
+
+	if q != (square{0, 0}) {
+		t.Errorf("the cycle ends on %v", q)
+	}
+
+	board[3][3] = tileWall
+	if _, ok := hamiltonianNext(q); ok {
+		t.Error("a board with a wall has a Hamiltonian cycle")
+	}
+}
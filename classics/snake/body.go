@@ -0,0 +1,155 @@
+package main
+
+import . "github.com/gen2brain/raylib-go/raylib"
+
+// ------------------------------------------------------------------------------------
+// Body of the snake
+// ------------------------------------------------------------------------------------
+//
+// The body has no maximum length: the positions of its segments are kept in a ring buffer that doubles its size
+// when it's full. Moving the snake only touches both ends of it: the new position of the head is pushed at the
+// front, and the last segment of the tail is popped from the back, so a move takes the same time at any length.
+// Eating a fruit pushes the popped segment back, and the snake grows by one square.
+//
+// The number of segments on every square of the board is kept up to date as well (see occupied), so finding
+// whether the head bites a tail (of any snake) doesn't have to walk the bodies either, and so are the free
+// squares the fruits can be put on (see freesquares.go).
+
+// snakeBody is a ring buffer of the positions (in pixels) of the segments of the snake.
+type snakeBody struct {
+	segments []Vector2 // The ring buffer, the segments that don't belong to the body are unused.
+	head     int       // Index of the head in segments, the tail follows it (wrapping around at the end).
+	length   int       // Number of segments of the body.
+}
+
+// reset makes a body of the given length, with all its segments on the same position.
+func (b *snakeBody) reset(position Vector2, length int) {
+	b.segments = make([]Vector2, length+16)
+	b.head = 0
+	b.length = length
+
+	for i := 0; i < length; i++ {
+		b.segments[i] = position
+	}
+}
+
+// len returns the number of segments of the body.
+func (b *snakeBody) len() int {
+	return b.length
+}
+
+// at returns the position of a segment: 0 is the head, len()-1 the end of the tail.
+func (b *snakeBody) at(i int) Vector2 {
+	return b.segments[(b.head+i)%len(b.segments)]
+}
+
+// pushHead adds a segment in front of the head, it becomes the new head.
+func (b *snakeBody) pushHead(position Vector2) {
+	b.grow()
+
+	b.head = (b.head + len(b.segments) - 1) % len(b.segments)
+	b.segments[b.head] = position
+	b.length++
+}
+
+// pushTail adds a segment after the end of the tail.
+func (b *snakeBody) pushTail(position Vector2) {
+	b.grow()
+
+	b.segments[(b.head+b.length)%len(b.segments)] = position
+	b.length++
+}
+
+// popTail removes the last segment of the tail, and returns its position.
+func (b *snakeBody) popTail() Vector2 {
+	b.length--
+
+	return b.segments[(b.head+b.length)%len(b.segments)]
+}
+
+// grow doubles the size of the ring buffer if it's full, keeping the segments in order.
+func (b *snakeBody) grow() {
+	if b.length < len(b.segments) {
+		return
+	}
+
+	segments := make([]Vector2, 2*len(b.segments))
+	for i := 0; i < b.length; i++ {
+		segments[i] = b.at(i)
+	}
+
+	b.segments = segments
+	b.head = 0
+}
+
+// clearBoard takes every snake off the board: every floor square is free (there must be no fruit on the board).
+func clearBoard() {
+	occupied = [gridColumns][gridRows]int{}
+	resetFreeSquares()
+}
+
+// place puts the whole body of the snake, of the given length, on a square of the board: it unfolds as
+// the snake moves.
+func (s *Snake) place(x, y, length int) {
+	s.body.reset(squarePosition(x, y), length)
+	s.lastTail = s.body.at(0)
+
+	for i := 0; i < length; i++ {
+		occupy(s.body.at(i))
+	}
+}
+
+// move moves the head of the snake to the given position (in pixels), and the tail follows it.
+func (s *Snake) move(head Vector2) {
+	s.body.pushHead(head)
+	occupy(head)
+
+	s.lastTail = s.body.popTail()
+	vacate(s.lastTail)
+}
+
+// grow adds a segment after the end of the tail, where the tail was before the last move.
+func (s *Snake) grow() {
+	s.body.pushTail(s.lastTail)
+	occupy(s.lastTail)
+}
+
+// remove takes the body of the snake off the board.
+func (s *Snake) remove() {
+	for i := 0; i < s.body.len(); i++ {
+		vacate(s.body.at(i))
+	}
+}
+
+// occupy counts a segment of the snake on the square at the given position (in pixels).
+func occupy(position Vector2) {
+	x, y, ok := positionSquare(position)
+	if !ok {
+		return
+	}
+
+	if occupied[x][y] == 0 {
+		takeSquare(x, y)
+	}
+	occupied[x][y]++
+}
+
+// vacate stops counting a segment of the snake on the square at the given position (in pixels).
+func vacate(position Vector2) {
+	x, y, ok := positionSquare(position)
+	if !ok {
+		return
+	}
+
+	occupied[x][y]--
+	if occupied[x][y] == 0 {
+		releaseSquare(x, y)
+	}
+}
+
+// hasBitten reports whether the head of the snake is on the same square as another segment, of any snake.
+func (s *Snake) hasBitten() bool {
+	x, y, ok := positionSquare(s.body.at(0))
+
+	return ok && occupied[x][y] > 1
+}
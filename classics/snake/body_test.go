@@ -0,0 +1,97 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/gen2brain/raylib-go/raylib"
+)
+
+// useEmptyBoard makes the board a floor without walls, snakes nor fruits for the rest of the test.
+func useEmptyBoard(t *testing.T) {
+	savedBoard, savedFruits, savedOccupied := board, fruits, occupied
+	savedFreeSquares, savedFreeIndex := append([]square(nil), freeSquares...), freeIndex
+
+	t.Cleanup(func() {
+		board, fruits, occupied = savedBoard, savedFruits, savedOccupied
+		freeSquares, freeIndex = savedFreeSquares, savedFreeIndex
+	})
+
+	board = [gridColumns][gridRows]tile{}
+	fruits = nil
+	clearBoard()
+}
+
+// The ring buffer keeps the segments in order while it grows and wraps around, like a plain list would.
+func TestSnakeBody(t *testing.T) {
+	var b snakeBody
+	b.reset(Vector2{}, 3)
+	want := []Vector2{{}, {}, {}}
+
+	for i := 1; i <= 100; i++ {
+		position := Vector2{X: float32(i)}
+
+		switch i % 4 {
+		case 0:
+			b.pushTail(position)
+			want = append(want, position)
+		case 1:
+			popped := b.popTail()
+			if popped != want[len(want)-1] {
+				t.Fatalf("step %d: popped %v, want %v", i, popped, want[len(want)-1])
+			}
+			want = want[:len(want)-1]
+		default:
+			b.pushHead(position)
+			want = append([]Vector2{position}, want...)
+		}
+
+		if b.len() != len(want) {
+			t.Fatalf("step %d: length %d, want %d", i, b.len(), len(want))
+		}
+
+		for k := range want {
+			if b.at(k) != want[k] {
+				t.Fatalf("step %d: segment %d at %v, want %v", i, k, b.at(k), want[k])
+			}
+		}
+	}
+}
+
+func TestOccupancy(t *testing.T) {
+	useEmptyBoard(t)
+
+	free := len(freeSquares)
+
+	var s Snake
+	s.place(2, 2, 3)
+
+	if occupied[2][2] != 3 || len(freeSquares) != free-1 {
+		t.Fatalf("%d segments on the spawn and %d free squares, want 3 and %d", occupied[2][2], len(freeSquares), free-1)
+	}
+
+	for x := 3; x <= 5; x++ {
+		s.move(squarePosition(x, 2))
+	}
+
+	if occupied[2][2] != 0 || occupied[3][2] != 1 || occupied[5][2] != 1 || len(freeSquares) != free-3 {
+		t.Fatalf("wrong occupancy after unfolding: %v", occupied[:6])
+	}
+
+	if s.hasBitten() {
+		t.Error("the unfolded snake has bitten itself")
+	}
+
+	// The snake grows where its tail was, and turns back into its own body
+	s.grow()
+	s.move(squarePosition(4, 2))
+
+	if occupied[2][2] != 0 || occupied[4][2] != 2 || !s.hasBitten() {
+		t.Errorf("the snake hasn't bitten itself: %v", occupied[:6])
+	}
+
+	s.remove()
+
+	if len(freeSquares) != free {
+		t.Errorf("%d free squares once the snake is removed, want %d", len(freeSquares), free)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import . "github.com/gen2brain/raylib-go/raylib"
+
+// ------------------------------------------------------------------------------------
+// Free squares
+// ------------------------------------------------------------------------------------
+//
+// A fruit is only put on a free square: a floor square (see levels.go) with neither the snake nor another fruit
+// on it. The free squares are kept in a list that is updated as the snake moves and the fruits come and go,
+// so a new fruit picks one of them at random right away, however crowded the board is. When there is no free
+// square left and the snake has eaten every fruit, it covers the whole board: the player has won.
+//
+// NOTE: The list is not in any particular order: a square is removed by moving the last one of the list
+//       in its place, and freeIndex tells where every square is in the list.
+
+// resetFreeSquares makes every floor square of the current board free.
+func resetFreeSquares() {
+	freeSquares = freeSquares[:0]
+
+	for x := 0; x < gridColumns; x++ {
+		for y := 0; y < gridRows; y++ {
+			freeIndex[x][y] = -1
+
+			if board[x][y] == tileFloor {
+				freeIndex[x][y] = len(freeSquares)
+				freeSquares = append(freeSquares, square{x: x, y: y})
+			}
+		}
+	}
+}
+
+// takeSquare removes a square from the free squares, if it's one of them.
+func takeSquare(x, y int) {
+	k := freeIndex[x][y]
+	if k < 0 {
+		return
+	}
+
+	last := freeSquares[len(freeSquares)-1]
+	freeSquares[k] = last
+	freeIndex[last.x][last.y] = k
+
+	freeSquares = freeSquares[:len(freeSquares)-1]
+	freeIndex[x][y] = -1
+}
+
+// releaseSquare adds a floor square back to the free squares, if it isn't one of them already and there is
+// nothing left on it: neither a snake nor a fruit.
+func releaseSquare(x, y int) {
+	if freeIndex[x][y] >= 0 || board[x][y] != tileFloor || occupied[x][y] > 0 || hasFruit(x, y) {
+		return
+	}
+
+	freeIndex[x][y] = len(freeSquares)
+	freeSquares = append(freeSquares, square{x: x, y: y})
+}
+
+// hasFruit reports whether there is a fruit on the square.
+func hasFruit(x, y int) bool {
+	for _, f := range fruits {
+		if fx, fy, _ := positionSquare(f.position); f.active && fx == x && fy == y {
+			return true
+		}
+	}
+
+	return false
+}
+
+// placeFruit puts a fruit of a random kind (see fruit.go) on a free square picked at random.
+// It returns false if there is no free square left.
+func placeFruit(f *Food) bool {
+	if len(freeSquares) == 0 {
+		return false
+	}
+
+	s := freeSquares[GetRandomValue(0, int32(len(freeSquares)-1))]
+	takeSquare(s.x, s.y)
+
+	f.active = true
+	f.position = squarePosition(s.x, s.y)
+	f.setKind(randomFruitKind())
+
+	return true
+}
+
+// isBoardFull reports whether the snake covers every floor square of the board: there is no free square left,
+// nor any fruit to eat.
+func isBoardFull() bool {
+	if len(freeSquares) > 0 {
+		return false
+	}
+
+	for _, f := range fruits {
+		if f.active {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,115 @@
+package main
+
+import "testing"
+
+// checkFreeSquares fails the test if freeIndex doesn't tell where every free square is.
+func checkFreeSquares(t *testing.T) {
+	t.Helper()
+
+	free := 0
+	for x := 0; x < gridColumns; x++ {
+		for y := 0; y < gridRows; y++ {
+			if k := freeIndex[x][y]; k >= 0 {
+				free++
+				if k >= len(freeSquares) || freeSquares[k] != (square{x, y}) {
+					t.Fatalf("square {%d %d} is not at %d in the free squares", x, y, k)
+				}
+			}
+		}
+	}
+
+	if free != len(freeSquares) {
+		t.Fatalf("%d squares have an index, but there are %d free squares", free, len(freeSquares))
+	}
+}
+
+func TestTakeAndReleaseSquares(t *testing.T) {
+	useEmptyBoard(t)
+
+	board[0][0] = tileWall
+	resetFreeSquares()
+	checkFreeSquares(t)
+
+	all := len(freeSquares)
+	if all != gridColumns*gridRows-1 {
+		t.Fatalf("%d free squares, want all but the wall", all)
+	}
+
+	takeSquare(3, 4)
+	takeSquare(3, 4)
+	takeSquare(0, 0)
+	checkFreeSquares(t)
+
+	if freeIndex[3][4] >= 0 || len(freeSquares) != all-1 {
+		t.Fatalf("{3 4} is still free (%d free squares)", len(freeSquares))
+	}
+
+	releaseSquare(3, 4)
+	releaseSquare(3, 4)
+	releaseSquare(0, 0)
+	checkFreeSquares(t)
+
+	if freeIndex[3][4] < 0 || len(freeSquares) != all {
+		t.Fatalf("{3 4} is not free again (%d free squares)", len(freeSquares))
+	}
+
+	// A square with a segment of a snake or a fruit on it stays taken
+	takeSquare(5, 5)
+	occupied[5][5] = 1
+	releaseSquare(5, 5)
+
+	fruits = []Food{{active: true, position: squarePosition(6, 6)}}
+	takeSquare(6, 6)
+	releaseSquare(6, 6)
+
+	checkFreeSquares(t)
+
+	if freeIndex[5][5] >= 0 || freeIndex[6][6] >= 0 {
+		t.Error("a square under the snake or a fruit has been released")
+	}
+}
+
+func TestPlaceFruit(t *testing.T) {
+	useEmptyBoard(t)
+
+	// Leave two free squares only
+	for x := 0; x < gridColumns; x++ {
+		for y := 0; y < gridRows; y++ {
+			if (x != 1 || y != 1) && (x != 7 || y != 2) {
+				takeSquare(x, y)
+			}
+		}
+	}
+
+	fruits = make([]Food, 3)
+
+	for k := 0; k < 2; k++ {
+		if !placeFruit(&fruits[k]) {
+			t.Fatalf("fruit %d has not been placed", k)
+		}
+
+		if x, y, _ := positionSquare(fruits[k].position); !fruits[k].active || (x != 1 || y != 1) && (x != 7 || y != 2) {
+			t.Fatalf("fruit %d placed on {%d %d}", k, x, y)
+		}
+	}
+
+	if fruits[0].position == fruits[1].position {
+		t.Fatal("both fruits are on the same square")
+	}
+
+	checkFreeSquares(t)
+
+	if placeFruit(&fruits[2]) || fruits[2].active {
+		t.Error("a fruit has been placed on a full board")
+	}
+
+	if isBoardFull() {
+		t.Error("the board is full, but there are fruits left to eat")
+	}
+
+	fruits[0].active, fruits[1].active = false, false
+
+	if !isBoardFull() {
+		t.Error("the board is not full")
+	}
+}
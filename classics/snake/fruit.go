@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+
+	. "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ------------------------------------------------------------------------------------
+// Fruits and power-ups
+// ------------------------------------------------------------------------------------
+//
+// Every fruit that appears is picked at random from the fruit table, following the spawn weights: the higher
+// the weight, the more often that kind of fruit shows up. Most fruits make the snake grow by one square, and
+// some of them have an effect that lasts for a few moves:
+//
+//	FAST   the snake moves twice as fast
+//	SLOW   the snake moves twice as slow
+//	GHOST  the head passes through the tails without biting them
+//
+// Speeding up cancels slowing down and the other way around. The golden fruit is worth more points, but it
+// rots if the snake doesn't get to it in time.
+
+type fruitKind int
+
+const (
+	fruitPlain fruitKind = iota
+	fruitGolden
+	fruitShrinking
+	fruitSpeedUp
+	fruitSlowDown
+	fruitGhost
+)
+
+type effect int
+
+const (
+	effectNone effect = iota
+	effectFast
+	effectSlow
+	effectGhost
+	effectCount // Number of effects (not an effect)
+)
+
+// effectNames are shown in the HUD while the effect lasts.
+var effectNames = [effectCount]string{"", "FAST", "SLOW", "GHOST"}
+
+// fruitType describes a kind of fruit.
+type fruitType struct {
+	color    Color
+	weight   int    // How often the fruit appears, compared with the weights of the other fruits.
+	worth    int    // Points, in fruitPoints (multiplied by the level as well).
+	growth   int    // Squares the snake grows (shrinks if negative) when it eats the fruit.
+	lifetime int    // Frames before the fruit rots and disappears (0: it doesn't).
+	effect   effect // Effect the fruit has on the snake.
+	moves    int    // Moves the effect lasts.
+}
+
+// fruitTypes is the fruit table.
+var fruitTypes = [...]fruitType{
+	fruitPlain:     {color: SkyBlue, weight: 60, worth: 1, growth: 1},
+	fruitGolden:    {color: Gold, weight: 10, worth: 5, growth: 1, lifetime: 5 * 60},
+	fruitShrinking: {color: Brown, weight: 10, worth: 1, growth: -3},
+	fruitSpeedUp:   {color: Red, weight: 8, worth: 1, growth: 1, effect: effectFast, moves: 30},
+	fruitSlowDown:  {color: Green, weight: 8, worth: 1, growth: 1, effect: effectSlow, moves: 30},
+	fruitGhost:     {color: Violet, weight: 4, worth: 1, growth: 1, effect: effectGhost, moves: 20},
+}
+
+// randomFruitKind picks a kind of fruit from the fruit table, following the spawn weights.
+func randomFruitKind() fruitKind {
+	total := 0
+	for _, t := range fruitTypes {
+		total += t.weight
+	}
+
+	roll := int(GetRandomValue(0, int32(total-1)))
+	for kind, t := range fruitTypes {
+		if roll < t.weight {
+			return fruitKind(kind)
+		}
+		roll -= t.weight
+	}
+
+	return fruitPlain
+}
+
+// setKind turns the fruit into the given kind of fruit.
+func (f *Food) setKind(kind fruitKind) {
+	f.kind = kind
+	f.color = fruitTypes[kind].color
+	f.timer = fruitTypes[kind].lifetime
+}
+
+// rotFruits counts down the lifetime of the fruits (one frame), and removes the ones that have rotted.
+func rotFruits() {
+	for k := range fruits {
+		f := &fruits[k]
+		if !f.active || fruitTypes[f.kind].lifetime == 0 {
+			continue
+		}
+
+		f.timer--
+		if f.timer <= 0 {
+			f.active = false
+
+			x, y, _ := positionSquare(f.position)
+			releaseSquare(x, y)
+		}
+	}
+}
+
+// eatFruit scores the fruit the snake has just eaten, and applies its growth and its effect.
+func eatFruit(s *Snake, f *Food) {
+	t := fruitTypes[f.kind]
+
+	f.active = false
+	s.score += fruitPoints * t.worth * level
+
+	for i := 0; i < t.growth; i++ {
+		s.grow()
+	}
+
+	// The snake always keeps its head
+	for i := 0; i > t.growth && s.body.len() > 1; i-- {
+		vacate(s.body.popTail())
+	}
+
+	switch t.effect {
+	case effectFast:
+		s.effectMoves[effectSlow] = 0
+	case effectSlow:
+		s.effectMoves[effectFast] = 0
+	}
+
+	if t.effect != effectNone {
+		s.effectMoves[t.effect] = t.moves
+	}
+}
+
+// wearOffEffects counts down the effects in place on the snake (one move).
+func (s *Snake) wearOffEffects() {
+	for e := range s.effectMoves {
+		if s.effectMoves[e] > 0 {
+			s.effectMoves[e]--
+		}
+	}
+}
+
+// isEffectActive reports whether the given effect is in place on the snake.
+func (s *Snake) isEffectActive(e effect) bool {
+	return s.effectMoves[e] > 0
+}
+
+// drawEffects draws the effects in place on the snake at the top of the screen, from the given horizontal
+// position, with the moves they have left. It returns where the text ends.
+func drawEffects(s *Snake, posX int) int {
+	for e := effectNone + 1; e < effectCount; e++ {
+		if !s.isEffectActive(e) {
+			continue
+		}
+
+		text := fmt.Sprintf("%s %d", effectNames[e], s.effectMoves[e])
+		drawText(text, posX, 2, 10, effectColor(e))
+		posX += measureText(text, 10) + 10
+	}
+
+	return posX
+}
+
+// effectColor returns the colour of the fruit that has the given effect.
+func effectColor(e effect) Color {
+	for _, t := range fruitTypes {
+		if t.effect == e {
+			return t.color
+		}
+	}
+
+	return DarkGray
+}
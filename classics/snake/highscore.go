@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ------------------------------------------------------------------------------------
+// High score
+// ------------------------------------------------------------------------------------
+//
+// The best score is kept in a small text file in the user's configuration directory
+// (e.g. ~/.config/raylib-go-games/snake-highscore on Linux), so it survives between sessions.
+
+// highScorePath returns the path of the file the high score is kept in.
+func highScorePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "." // No configuration directory: keep it next to the game
+	}
+
+	return filepath.Join(dir, "raylib-go-games", "snake-highscore")
+}
+
+// loadHighScore reads the high score, which is 0 if it has never been saved.
+func loadHighScore() int {
+	data, err := os.ReadFile(highScorePath())
+	if err != nil {
+		return 0
+	}
+
+	score, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0
+	}
+
+	return score
+}
+
+// saveHighScore writes the high score, so that the next sessions remember it.
+func saveHighScore() error {
+	path := highScorePath()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("saving the high score: %w", err)
+	}
+
+	if err := os.WriteFile(path, []byte(strconv.Itoa(highScore)+"\n"), 0o644); err != nil {
+		return fmt.Errorf("saving the high score: %w", err)
+	}
+
+	return nil
+}
+
+// updateHighScore is called when the game is over: it keeps the score if it's a new record.
+// NOTE: Only the single player games count for the high score, and only if the autopilot hasn't played them.
+func updateHighScore() {
+	score := snakes[0].score
+	if score <= highScore || hasAutopilotPlayed {
+		return
+	}
+
+	highScore = score
+	isNewHighScore = true
+
+	if err := saveHighScore(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import . "github.com/gen2brain/raylib-go/raylib"
+
+// ------------------------------------------------------------------------------------
+// Input queue
+// ------------------------------------------------------------------------------------
+//
+// The snake only turns when it moves, but the player may press several keys in between (e.g. [UP] then [LEFT]
+// for a quick U-turn). The turns are queued and applied one per move, so none of them is lost. A turn is
+// rejected if it goes along the direction the snake will have by then: the same direction does nothing,
+// and the opposite one would make the snake bite its own neck.
+
+const maxQueuedTurns = 3 // Number of turns the player can press ahead of the snake
+
+// readTurns queues the turns the player has pressed during this frame, with the keys of the snake (see players.go).
+func (s *Snake) readTurns() {
+	if IsKeyPressed(s.keys.right) {
+		s.queueTurn(Vector2{X: float32(SQUARE_SIZE)})
+	}
+	if IsKeyPressed(s.keys.left) {
+		s.queueTurn(Vector2{X: float32(-SQUARE_SIZE)})
+	}
+	if IsKeyPressed(s.keys.up) {
+		s.queueTurn(Vector2{Y: float32(-SQUARE_SIZE)})
+	}
+	if IsKeyPressed(s.keys.down) {
+		s.queueTurn(Vector2{Y: float32(SQUARE_SIZE)})
+	}
+}
+
+// queueTurn adds a turn to the queue, unless the queue is full or the turn goes along the direction
+// in effect once the turns already queued are applied.
+func (s *Snake) queueTurn(direction Vector2) {
+	if len(s.turnQueue) >= maxQueuedTurns {
+		return
+	}
+
+	last := s.speed
+	if len(s.turnQueue) > 0 {
+		last = s.turnQueue[len(s.turnQueue)-1]
+	}
+
+	if (direction.X != 0 && last.X != 0) || (direction.Y != 0 && last.Y != 0) {
+		return
+	}
+
+	s.turnQueue = append(s.turnQueue, direction)
+}
+
+// applyNextTurn turns the snake with the first turn of the queue, if any. It's called once per move.
+func (s *Snake) applyNextTurn() {
+	if len(s.turnQueue) == 0 {
+		return
+	}
+
+	s.speed = s.turnQueue[0]
+	s.turnQueue = s.turnQueue[1:]
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/gen2brain/raylib-go/raylib"
+)
+
+var (
+	turnRight = Vector2{X: float32(SQUARE_SIZE)}
+	turnLeft  = Vector2{X: float32(-SQUARE_SIZE)}
+	turnUp    = Vector2{Y: float32(-SQUARE_SIZE)}
+	turnDown  = Vector2{Y: float32(SQUARE_SIZE)}
+)
+
+func TestQueueTurn(t *testing.T) {
+	tests := []struct {
+		name  string
+		turns []Vector2
+		want  []Vector2
+	}{
+		{"same direction", []Vector2{turnRight}, nil},
+		{"backwards", []Vector2{turnLeft}, nil},
+		{"one turn", []Vector2{turnUp}, []Vector2{turnUp}},
+		{"U-turn", []Vector2{turnUp, turnLeft}, []Vector2{turnUp, turnLeft}},
+		{"back into the neck after a turn", []Vector2{turnUp, turnDown}, []Vector2{turnUp}},
+		{"zigzag", []Vector2{turnUp, turnRight, turnDown}, []Vector2{turnUp, turnRight, turnDown}},
+		{"full queue", []Vector2{turnUp, turnRight, turnDown, turnLeft}, []Vector2{turnUp, turnRight, turnDown}},
+	}
+
+	for _, tt := range tests {
+		s := Snake{speed: turnRight}
+		for _, turn := range tt.turns {
+			s.queueTurn(turn)
+		}
+
+		if !reflect.DeepEqual(s.turnQueue, tt.want) {
+			t.Errorf("%s: queued %v, want %v", tt.name, s.turnQueue, tt.want)
+		}
+	}
+}
+
+// The snake takes one queued turn per move, in order.
+func TestApplyNextTurn(t *testing.T) {
+	s := Snake{speed: turnRight}
+	s.queueTurn(turnUp)
+	s.queueTurn(turnLeft)
+
+	for _, want := range []Vector2{turnUp, turnLeft, turnLeft} {
+		s.applyNextTurn()
+
+		if s.speed != want {
+			t.Fatalf("speed %v, want %v", s.speed, want)
+		}
+	}
+
+	if len(s.turnQueue) != 0 {
+		t.Errorf("%d turns left in the queue", len(s.turnQueue))
+	}
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	. "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ------------------------------------------------------------------------------------
+// Levels
+// ------------------------------------------------------------------------------------
+//
+// The boards are loaded from level files: text files with one character per square of the board, gridColumns
+// characters per row and gridRows rows:
+//
+//	#  wall, the snake dies if it bumps into it
+//	.  floor
+//	S  spawn, where the snake starts (exactly one per level)
+//	P  portal, they go in pairs: the first two portals of the file (from top to bottom and from left to right)
+//	   are a pair, the next two another one, and so on
+//
+// The levels in the levels directory are bundled with the game, the -levels flag plays the ones found in another
+// directory instead. The player chooses the first level in the level select screen, and then every fruitsPerLevel
+// fruits the next one loads: the snake starts again from the spawn (keeping its length, so it keeps its speed too).
+// Once the last level is cleared, the levels start over from the first one.
+
+const (
+	fruitsPerLevel = 10 // Fruits to eat to load the next level
+	fruitPoints    = 10 // Points per fruit, multiplied by the level
+
+	gridColumns = screenWidth / SQUARE_SIZE  // Number of squares of the board, horizontally
+	gridRows    = screenHeight / SQUARE_SIZE // Number of squares of the board, vertically
+)
+
+//go:embed levels/*.txt
+var bundledLevels embed.FS
+
+// tile is what a square of the board is made of.
+type tile int
+
+const (
+	tileFloor tile = iota
+	tileWall
+	tilePortal
+)
+
+// square is the position of a square of the board (not in pixels).
+type square struct {
+	x, y int
+}
+
+// levelDef describes a board to play on.
+type levelDef struct {
+	name    string                      // Name shown in the level select screen, from the name of the file.
+	tiles   [gridColumns][gridRows]tile // What every square of the board is made of.
+	spawn   square                      // Where the snake starts.
+	portals [][2]square                 // Pairs of portals.
+}
+
+// loadLevels reads all the level files (*.txt) of the given directory, or the bundled levels if it's empty.
+// The levels are sorted by file name.
+func loadLevels(dir string) error {
+	var files fs.FS = os.DirFS(dir)
+	if dir == "" {
+		files, _ = fs.Sub(bundledLevels, "levels")
+	}
+
+	names, err := fs.Glob(files, "*.txt")
+	if err != nil {
+		return err
+	}
+
+	if len(names) == 0 {
+		return fmt.Errorf("no level files (*.txt) found in %s", dir)
+	}
+
+	levels = levels[:0]
+	for _, name := range names {
+		data, err := fs.ReadFile(files, name)
+		if err != nil {
+			return err
+		}
+
+		l, err := parseLevel(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path.Join(dir, name), err)
+		}
+
+		// "01-the-bar.txt" is called "THE BAR"
+		l.name = strings.TrimLeft(strings.TrimSuffix(name, ".txt"), "0123456789-_ ")
+		l.name = strings.ToUpper(strings.NewReplacer("-", " ", "_", " ").Replace(l.name))
+
+		levels = append(levels, l)
+	}
+
+	return nil
+}
+
+// parseLevel reads a level from a level file (see the format above).
+func parseLevel(data []byte) (levelDef, error) {
+	var (
+		l        levelDef
+		rows     int
+		hasSpawn bool
+		portals  []square // Portals waiting for their partner
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" {
+			continue
+		}
+
+		if rows == gridRows {
+			return l, fmt.Errorf("the board has more than %d rows", gridRows)
+		}
+
+		if len(line) != gridColumns {
+			return l, fmt.Errorf("row %d has %d squares, a row has %d", rows+1, len(line), gridColumns)
+		}
+
+		for x, c := range line {
+			switch c {
+			case '.':
+				l.tiles[x][rows] = tileFloor
+			case '#':
+				l.tiles[x][rows] = tileWall
+			case 'S':
+				if hasSpawn {
+					return l, fmt.Errorf("row %d: a level has only one spawn (S)", rows+1)
+				}
+
+				l.spawn = square{x: x, y: rows}
+				hasSpawn = true
+			case 'P':
+				l.tiles[x][rows] = tilePortal
+
+				portals = append(portals, square{x: x, y: rows})
+				if len(portals) == 2 {
+					l.portals = append(l.portals, [2]square{portals[0], portals[1]})
+					portals = portals[:0]
+				}
+			default:
+				return l, fmt.Errorf("row %d: unexpected %q (valid: # wall, . floor, S spawn, P portal)", rows+1, c)
+			}
+		}
+
+		rows++
+	}
+
+	switch {
+	case rows != gridRows:
+		return l, fmt.Errorf("the board has %d rows, it must have %d", rows, gridRows)
+	case !hasSpawn:
+		return l, fmt.Errorf("the level has no spawn (S)")
+	case len(portals) > 0:
+		return l, fmt.Errorf("the portal at row %d, column %d has no partner", portals[0].y+1, portals[0].x+1)
+	}
+
+	return l, nil
+}
+
+// loadLevel makes the given level (the first one is 1) the current one: it builds its board, and puts the snakes
+// back at their spawns (see players.go).
+func loadLevel(number int) {
+	level = number
+	board = currentLevel().tiles
+
+	for k := range fruits {
+		fruits[k].active = false
+	}
+
+	clearBoard()
+
+	for k, spawn := range spawnSquares(len(snakes)) {
+		s := &snakes[k]
+
+		// The whole body starts on the same square, and unfolds as the snake moves
+		s.place(spawn.x, spawn.y, s.body.len())
+		s.isAlive = true
+
+		// Head to the first direction that doesn't bump into a wall right away
+		for _, direction := range [...]square{{1, 0}, {0, 1}, {-1, 0}, {0, -1}} {
+			s.speed = Vector2{X: float32(direction.x * SQUARE_SIZE), Y: float32(direction.y * SQUARE_SIZE)}
+
+			if x, y, ok := positionSquare(squarePosition(spawn.x+direction.x, spawn.y+direction.y)); ok && board[x][y] != tileWall {
+				break
+			}
+		}
+
+		s.turnQueue = s.turnQueue[:0]
+		s.moveCounter = 0
+	}
+}
+
+// currentLevel returns the level being played.
+func currentLevel() *levelDef {
+	return &levels[(level-1)%len(levels)]
+}
+
+// squarePosition returns the position on the screen (in pixels) of a square of the board.
+func squarePosition(x, y int) Vector2 {
+	return Vector2{
+		X: float32(x*SQUARE_SIZE) + offset.X/2,
+		Y: float32(y*SQUARE_SIZE) + offset.Y/2,
+	}
+}
+
+// positionSquare returns the square of the board at the given position on the screen (in pixels),
+// and whether it's inside the board.
+func positionSquare(position Vector2) (x, y int, ok bool) {
+	x = int(position.X-offset.X/2) / SQUARE_SIZE
+	y = int(position.Y-offset.Y/2) / SQUARE_SIZE
+
+	isInside := position.X >= offset.X/2 && position.Y >= offset.Y/2 && x < gridColumns && y < gridRows
+
+	return x, y, isInside
+}
+
+// portalColors tell the pairs of portals apart.
+var portalColors = [...]Color{Purple, Orange, Lime, Pink}
+
+// drawBoard draws the walls and the portals of the current level.
+func drawBoard() {
+	size := Vector2{X: float32(SQUARE_SIZE), Y: float32(SQUARE_SIZE)}
+
+	for x := 0; x < gridColumns; x++ {
+		for y := 0; y < gridRows; y++ {
+			if board[x][y] == tileWall {
+				DrawRectangleV(squarePosition(x, y), size, DarkGray)
+			}
+		}
+	}
+
+	for k, pair := range currentLevel().portals {
+		for _, portal := range pair {
+			DrawRectangleV(squarePosition(portal.x, portal.y), size, portalColors[k%len(portalColors)])
+		}
+	}
+}
+
+// updateLevelSelect lets the player pick the first level with the arrow keys and start playing with [ENTER].
+// [W] toggles the wrap-around mode (see wrap.go), [LEFT] and [RIGHT] choose the number of players (see players.go).
+func updateLevelSelect() {
+	if IsKeyPressed(KeyUp) {
+		selectedLevel = (selectedLevel + len(levels) - 1) % len(levels)
+	}
+
+	if IsKeyPressed(KeyDown) {
+		selectedLevel = (selectedLevel + 1) % len(levels)
+	}
+
+	if IsKeyPressed(KeyW) {
+		isWrapAround = !isWrapAround
+	}
+
+	if IsKeyPressed(KeyLeft) && playerCount > 1 {
+		playerCount--
+	}
+
+	if IsKeyPressed(KeyRight) && playerCount < maxPlayers {
+		playerCount++
+	}
+
+	if IsKeyPressed(KeyEnter) {
+		InitGame()
+		isSelectingLevel = false
+	}
+}
+
+// drawLevelSelect draws the list of levels, highlighting the selected one.
+// NOTE: Only a few levels fit on the screen, the list scrolls to keep the selected one in sight.
+func drawLevelSelect() {
+	const visibleLevels = 8
+
+	drawText("SNAKE", screenWidth/2-measureText("SNAKE", 40)/2, 40, 40, DarkGray)
+
+	first := selectedLevel - visibleLevels/2
+	if first > len(levels)-visibleLevels {
+		first = len(levels) - visibleLevels
+	}
+
+	if first < 0 {
+		first = 0
+	}
+
+	for k := first; k < len(levels) && k < first+visibleLevels; k++ {
+		name := levels[k].name
+
+		col := LightGray
+		if k == selectedLevel {
+			col = Maroon
+			name = "> " + name + " <"
+		}
+
+		drawText(name, screenWidth/2-measureText(name, 20)/2, 110+(k-first)*30, 20, col)
+	}
+
+	wrap := "WRAP-AROUND: OFF [W]"
+	if isWrapAround {
+		wrap = "WRAP-AROUND: ON [W]"
+	}
+	drawText(wrap, screenWidth/2-measureText(wrap, 10)/2, 335, 10, Gray)
+
+	players := fmt.Sprintf("PLAYERS: %d [LEFT]/[RIGHT]", playerCount)
+	drawText(players, screenWidth/2-measureText(players, 10)/2, 350, 10, Gray)
+
+	record := fmt.Sprintf("HIGH SCORE: %d", highScore)
+	drawText(record, screenWidth/2-measureText(record, 10)/2, 365, 10, Gray)
+
+	const startMsg = "PRESS [UP]/[DOWN] TO CHOOSE A LEVEL AND [ENTER] TO PLAY"
+	drawText(startMsg, screenWidth/2-measureText(startMsg, 20)/2, 390, 20, Gray)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// levelRows returns the rows of a level file: floor everywhere, except for the given squares.
+func levelRows(squares map[square]byte) []string {
+	rows := make([]string, gridRows)
+	for y := range rows {
+		row := []byte(strings.Repeat(".", gridColumns))
+		for s, c := range squares {
+			if s.y == y {
+				row[s.x] = c
+			}
+		}
+
+		rows[y] = string(row)
+	}
+
+	return rows
+}
+
+func TestParseLevel(t *testing.T) {
+	valid := levelRows(map[square]byte{{0, 0}: '#', {5, 3}: 'S', {1, 1}: 'P', {9, 2}: 'P', {3, 4}: 'P', {2, 7}: 'P'})
+
+	l, err := parseLevel([]byte(strings.Join(valid, "\n")))
+	if err != nil {
+		t.Fatalf("parseLevel(): %v", err)
+	}
+
+	if l.spawn != (square{5, 3}) {
+		t.Errorf("spawn at %v, want {5 3}", l.spawn)
+	}
+
+	// The spawn is floor
+	if l.tiles[0][0] != tileWall || l.tiles[5][3] != tileFloor || l.tiles[1][1] != tilePortal || l.tiles[1][0] != tileFloor {
+		t.Errorf("wrong tiles")
+	}
+
+	// The portals are paired from top to bottom, and from left to right
+	wantPortals := [][2]square{{{1, 1}, {9, 2}}, {{3, 4}, {2, 7}}}
+	if !reflect.DeepEqual(l.portals, wantPortals) {
+		t.Errorf("portals %v, want %v", l.portals, wantPortals)
+	}
+}
+
+func TestParseLevelErrors(t *testing.T) {
+	spawn := map[square]byte{{5, 3}: 'S'}
+
+	tests := []struct {
+		name string
+		rows []string
+		ok   bool
+	}{
+		{"valid", levelRows(spawn), true},
+		{"blank lines and trailing spaces", append(append([]string{""}, levelRows(spawn)...), "  "), true},
+		{"no spawn", levelRows(nil), false},
+		{"two spawns", levelRows(map[square]byte{{5, 3}: 'S', {6, 3}: 'S'}), false},
+		{"portal without a partner", levelRows(map[square]byte{{5, 3}: 'S', {1, 1}: 'P', {2, 2}: 'P', {3, 3}: 'P'}), false},
+		{"unknown square", levelRows(map[square]byte{{5, 3}: 'S', {1, 1}: 'X'}), false},
+		{"missing row", levelRows(spawn)[1:], false},
+		{"extra row", append(levelRows(spawn), strings.Repeat(".", gridColumns)), false},
+		{"short row", append(levelRows(spawn)[1:], strings.Repeat(".", gridColumns-1)), false},
+	}
+
+	for _, tt := range tests {
+		_, err := parseLevel([]byte(strings.Join(tt.rows, "\n")))
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("%s: parseLevel() error %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestBundledLevels(t *testing.T) {
+	if err := loadLevels(""); err != nil {
+		t.Fatal(err)
+	}
+
+	if levels[0].name != "OPEN FIELD" {
+		t.Errorf("the first bundled level is %q, want \"OPEN FIELD\"", levels[0].name)
+	}
+}
@@ -14,7 +14,10 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"image/color"
+	"os"
 
 	. "github.com/gen2brain/raylib-go/raylib"
 	"golang.org/x/exp/constraints"
@@ -23,17 +26,25 @@ import (
 // ----------------------------------------------------------------------------------
 // Some Defines
 // ----------------------------------------------------------------------------------
-const SNAKE_LENGTH int = 256
 const SQUARE_SIZE int = 31
 
 // ----------------------------------------------------------------------------------
 // Types and Structures Definition
 // ----------------------------------------------------------------------------------
 type Snake struct {
-	position Vector2
-	size     Vector2
-	speed    Vector2
-	color    Color
+	body        snakeBody // Positions of the segments, from the head to the end of the tail (see body.go)
+	lastTail    Vector2   // Position the end of the tail has left on the last move: the snake grows from there
+	size        Vector2
+	speed       Vector2
+	headColor   Color
+	color       Color
+	keys        keySet           // Keys that turn the snake (see players.go)
+	turnQueue   []Vector2        // Turns pressed by the player, waiting for the snake to move (see input.go)
+	moveCounter int              // Frames since the snake last moved (see framesPerMove)
+	effectMoves [effectCount]int // Moves left for every effect of the fruits to wear off (see fruit.go)
+	score       int
+	isAlive     bool
+	roundsWon   int // Rounds won in a multiplayer match (see players.go)
 }
 
 type Food struct {
@@ -41,6 +52,8 @@ type Food struct {
 	size     Vector2
 	active   bool
 	color    Color
+	kind     fruitKind // Kind of fruit, from the fruit table (see fruit.go)
+	timer    int       // Frames left before the fruit rots (see fruitType.lifetime)
 }
 
 // ------------------------------------------------------------------------------------
@@ -53,17 +66,105 @@ var framesCounter = 0
 var gameOver = false
 var pause = false
 
-var fruit = Food{}
-var snake = [SNAKE_LENGTH]Snake{}
-var snakePosition = [SNAKE_LENGTH]Vector2{}
-var allowMove = false
+var fruits []Food
+var snakes []Snake // One per player, the first one is the snake of the single player games (see players.go)
 var offset = Vector2{}
-var counterTail = 0
+
+// Score and levels (see levels.go)
+var fruitsEaten = 0
+var level = 1
+var highScore = 0          // Best score ever (see highscore.go)
+var isNewHighScore = false // has the last game beaten the high score?
+var isVictory = false      // has the snake filled the board? (see freesquares.go)
+
+// Multiplayer match (see players.go)
+var round = 1           // Round being played, the first one is 1.
+var roundWinner = -1    // Player who has won the last round (-1: nobody).
+var isMatchOver = false // are the final standings shown? (after the last round)
+
+// Squares taken by the snakes (see body.go)
+var occupied = [gridColumns][gridRows]int{} // Number of segments of the snakes on every square of the board.
+
+// Free squares, where the fruits can be put (see freesquares.go)
+var freeSquares []square                     // Floor squares with neither the snake nor a fruit on them.
+var freeIndex = [gridColumns][gridRows]int{} // Where every square is in freeSquares (-1 if it's not free).
+
+// Levels (see levels.go)
+var levels []levelDef                     // Levels loaded from the level files, in order.
+var board = [gridColumns][gridRows]tile{} // Tiles of the current level.
+var selectedLevel = 0                     // Level highlighted in the level select screen (the first one is 0).
+var isSelectingLevel = true               // is the player choosing the level? (before the game starts)
+
+// Settings (see the command line flags in main)
+var speed = speedCurves["normal"] // How the speed of the snake rises with its length (see speed.go)
+var isWrapAround = false          // do the edges of the board lead to the opposite side? (see wrap.go)
+var fruitCount = 1                // Number of fruits on the board at the same time
+var playerCount = 1               // Number of snakes on the board (see players.go)
+var roundsPerMatch = 3            // Rounds of a multiplayer match (see players.go)
+
+// Autopilot (see autopilot.go)
+var isAutopilot = false        // does the autopilot steer the snake of the first player?
+var hasAutopilotPlayed = false // has the autopilot steered the snake during this game? (then it doesn't count for the high score)
+var autopilotPath []square     // Path planned by the autopilot, drawn over the board.
 
 // ------------------------------------------------------------------------------------
 // Program main entry point
 // ------------------------------------------------------------------------------------
 func main() {
+	speedName := flag.String("speed", "normal", "speed curve: fixed, normal or hard")
+	flag.BoolVar(&isWrapAround, "wrap", isWrapAround, "wrap-around mode: the edges of the board lead to the opposite side")
+	levelsDir := flag.String("levels", "", "directory with the level files to play (default: the bundled levels)")
+	flag.IntVar(&fruitCount, "fruits", fruitCount, "number of fruits on the board at the same time")
+	flag.IntVar(&playerCount, "players", playerCount, "number of players, each one with its own snake (1 to 4)")
+	flag.IntVar(&roundsPerMatch, "rounds", roundsPerMatch, "rounds of a multiplayer match")
+	flag.BoolVar(&isAutopilot, "autopilot", isAutopilot, "let the autopilot steer the snake of the first player (toggle it with [TAB])")
+	headless := flag.Bool("headless", false, "let the autopilot play without opening a window, and print the results")
+	games := flag.Int("games", 10, "number of games the autopilot plays in headless mode (one random seed per game)")
+	flag.Parse()
+
+	if fruitCount < 1 {
+		fmt.Fprintln(os.Stderr, "the number of fruits must be at least 1")
+		os.Exit(2)
+	}
+
+	if playerCount < 1 || playerCount > maxPlayers {
+		fmt.Fprintf(os.Stderr, "the number of players must be between 1 and %d\n", maxPlayers)
+		os.Exit(2)
+	}
+
+	if roundsPerMatch < 1 {
+		fmt.Fprintln(os.Stderr, "the number of rounds must be at least 1")
+		os.Exit(2)
+	}
+
+	var err error
+	if speed, err = findSpeedCurve(*speedName); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if err = loadLevels(*levelsDir); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if *headless {
+		if *games < 1 {
+			fmt.Fprintln(os.Stderr, "invalid number of games: it must be 1 or more")
+			os.Exit(2)
+		}
+
+		if playerCount != 1 {
+			fmt.Fprintln(os.Stderr, "the autopilot only plays single player games in headless mode")
+			os.Exit(2)
+		}
+
+		runHeadless(*games)
+		return
+	}
+
+	highScore = loadHighScore()
+
 	// Initialization (Note windowTitle is unused on Android)
 	//---------------------------------------------------------
 	InitWindow(screenWidth, screenHeight, "classic game: snake")
@@ -92,124 +193,131 @@ func main() {
 // Initialize game variables
 func InitGame() {
 	framesCounter = 0
-	gameOver = false
 	pause = false
 
-	counterTail = 1
-	allowMove = false
+	fruitsEaten = 0
+	isNewHighScore = false
+
+	round = 1
+	isMatchOver = false
+
+	hasAutopilotPlayed = false
+	autopilotPath = nil
 
 	offset.X = float32(screenHeight % SQUARE_SIZE)
 	offset.Y = float32(screenHeight % SQUARE_SIZE)
 
-	for i := 0; i < SNAKE_LENGTH; i++ {
-		snake[i].position = Vector2{X: offset.X / 2, Y: offset.Y / 2}
-		snake[i].size = Vector2{X: float32(SQUARE_SIZE), Y: float32(SQUARE_SIZE)}
-		snake[i].speed = Vector2{X: float32(SQUARE_SIZE)}
-
-		if i == 0 {
-			snake[i].color = DarkBlue
-		} else {
-			snake[i].color = Blue
-		}
-	}
+	snakes = newSnakes()
 
-	for i := 0; i < SNAKE_LENGTH; i++ {
-		snakePosition[i] = Vector2{}
+	fruits = make([]Food, fruitCount)
+	for k := range fruits {
+		fruits[k].size = Vector2{X: float32(SQUARE_SIZE), Y: float32(SQUARE_SIZE)}
+		fruits[k].setKind(fruitPlain)
 	}
 
-	fruit.size = Vector2{X: float32(SQUARE_SIZE), Y: float32(SQUARE_SIZE)}
-	fruit.color = SkyBlue
-	fruit.active = false
+	// Start from the level chosen by the player (it also places the snakes, see levels.go)
+	startRound()
 }
 
 // Update game (one frame)
 func UpdateGame() {
+	// Before playing, the player has to choose a level
+	if isSelectingLevel {
+		updateLevelSelect()
+		return
+	}
+
 	if !gameOver {
 		if IsKeyPressed(KeyP) {
 			pause = !pause
 		}
 
-		if !pause {
-			// Player control
-			if IsKeyPressed(KeyRight) && (snake[0].speed.X == 0) && allowMove {
-				snake[0].speed = Vector2{X: float32(SQUARE_SIZE)}
-				allowMove = false
-			}
-			if IsKeyPressed(KeyLeft) && (snake[0].speed.X == 0) && allowMove {
-				snake[0].speed = Vector2{X: float32(-SQUARE_SIZE)}
-				allowMove = false
-			}
-			if IsKeyPressed(KeyUp) && (snake[0].speed.Y == 0) && allowMove {
-				snake[0].speed = Vector2{Y: float32(-SQUARE_SIZE)}
-				allowMove = false
-			}
-			if IsKeyPressed(KeyDown) && (snake[0].speed.Y == 0) && allowMove {
-				snake[0].speed = Vector2{Y: float32(SQUARE_SIZE)}
-				allowMove = false
-			}
+		updateAutopilot()
 
-			// Snake movement
-			for i := 0; i < counterTail; i++ {
-				snakePosition[i] = snake[i].position
-			}
+		if !pause {
+			for k := range snakes {
+				s := &snakes[k]
+				if !s.isAlive {
+					continue
+				}
 
-			if (framesCounter % 5) == 0 {
-				for i := 0; i < counterTail; i++ {
-					if i == 0 {
-						snake[0].position.X += snake[0].speed.X
-						snake[0].position.Y += snake[0].speed.Y
-						allowMove = true
-					} else {
-						snake[i].position = snakePosition[i-1]
-					}
+				// Player control: the turns are queued, and applied one per move (see input.go)
+				if k == 0 && isAutopilot {
+					s.steer() // The autopilot plays instead of the first player (see autopilot.go)
+				} else {
+					s.readTurns()
 				}
-			}
 
-			// Wall behaviour
-			if ((snake[0].position.X) > (screenWidth - offset.X)) || ((snake[0].position.Y) > (screenHeight - offset.Y)) || (snake[0].position.X < 0) || (snake[0].position.Y < 0) {
-				gameOver = true
-			}
+				// Snake movement: the head moves forward, and the end of the tail is left behind (see body.go)
+				// The snake moves faster as it grows (see speed.go)
+				s.moveCounter++
+				if s.moveCounter >= s.framesPerMove() {
+					s.moveCounter = 0
 
-			// Collision with yourself
-			for i := 1; i < counterTail; i++ {
-				if (snake[0].position.X == snake[i].position.X) && (snake[0].position.Y == snake[i].position.Y) {
-					gameOver = true
+					s.applyNextTurn()
+					s.moveHead() // Through the edges and the portals (see wrap.go)
+					s.wearOffEffects()
 				}
 			}
 
-			// Fruit position calculation
-			if !fruit.active {
-				fruit.active = true
-				fruit.position = Vector2{
-					X: float32(GetRandomValue(0, (screenWidth/int32(SQUARE_SIZE))-1)*int32(SQUARE_SIZE) + int32(offset.X/2)),
-					Y: float32(GetRandomValue(0, (screenHeight/int32(SQUARE_SIZE))-1)*int32(SQUARE_SIZE) + int32(offset.Y/2)),
-				}
+			// Golden fruits rot if the snakes take too long to get them (see fruit.go)
+			rotFruits()
+
+			// Walls, bodies and heads of the other snakes (see players.go)
+			updateCollisions()
 
-				for i := 0; i < counterTail; i++ {
-					for (fruit.position.X == snake[i].position.X) && (fruit.position.Y == snake[i].position.Y) {
-						fruit.position = Vector2{
-							X: float32(GetRandomValue(0, (screenWidth/int32(SQUARE_SIZE))-1)*int32(SQUARE_SIZE) + int32(offset.X/2)),
-							Y: float32(GetRandomValue(0, (screenHeight/int32(SQUARE_SIZE))-1)*int32(SQUARE_SIZE) + int32(offset.Y/2)),
+			// Collision
+			for k := range snakes {
+				s := &snakes[k]
+				head := s.body.at(0)
+
+				for j := range fruits {
+					f := &fruits[j]
+					if s.isAlive && f.active && (head.X < (f.position.X+f.size.X) && (head.X+s.size.X) > f.position.X) && (head.Y < (f.position.Y+f.size.Y) && (head.Y+s.size.Y) > f.position.Y) {
+						eatFruit(s, f) // Every kind of fruit has its own points and effect (see fruit.go)
+						fruitsEaten++
+
+						// A multiplayer match is played on a single level
+						if !isMultiplayer() && fruitsEaten%fruitsPerLevel == 0 {
+							loadLevel(level + 1)
 						}
-						i = 0
 					}
 				}
 			}
 
-			// Collision
-			if (snake[0].position.X < (fruit.position.X+fruit.size.X) && (snake[0].position.X+snake[0].size.X) > fruit.position.X) && (snake[0].position.Y < (fruit.position.Y+fruit.size.Y) && (snake[0].position.Y+snake[0].size.Y) > fruit.position.Y) {
-				snake[counterTail].position = snakePosition[counterTail-1]
-				counterTail += 1
-				fruit.active = false
+			// Fruit position calculation: on one of the free squares (see freesquares.go)
+			for k := range fruits {
+				if !fruits[k].active {
+					placeFruit(&fruits[k])
+				}
+			}
+
+			switch {
+			case countAliveSnakes() == 0, isMultiplayer() && countAliveSnakes() == 1:
+				gameOver = true
+			case isBoardFull():
+				// No room left for a fruit, and no fruit left to eat: the snakes cover the whole board
+				gameOver = true
+				isVictory = true
 			}
 
 			framesCounter++
+
+			if gameOver {
+				if isMultiplayer() {
+					endRound()
+				} else {
+					updateHighScore()
+				}
+			}
 		}
 
+	} else if isMultiplayer() {
+		updateRoundOver()
 	} else {
 		if IsKeyPressed(KeyEnter) {
 			InitGame()
-			gameOver = false
+			isSelectingLevel = true
 		}
 	}
 }
@@ -220,7 +328,9 @@ func DrawGame() {
 
 	ClearBackground(RayWhite)
 
-	if !gameOver {
+	if isSelectingLevel {
+		drawLevelSelect()
+	} else if !gameOver {
 		// Draw grid lines
 		for i := 0; i < screenWidth/SQUARE_SIZE+1; i++ {
 			DrawLineV(
@@ -236,25 +346,88 @@ func DrawGame() {
 				LightGray)
 		}
 
-		// Draw snake
-		for i := 0; i < counterTail; i++ {
-			DrawRectangleV(snake[i].position, snake[i].size, snake[i].color)
+		drawBoard()
+
+		// Draw snakes
+		for _, s := range snakes {
+			if !s.isAlive {
+				continue
+			}
+
+			for i := s.body.len() - 1; i > 0; i-- {
+				DrawRectangleV(s.body.at(i), s.size, s.color)
+			}
+			DrawRectangleV(s.body.at(0), s.size, s.headColor)
 		}
 
-		// Draw fruit to pick
-		DrawRectangleV(fruit.position, fruit.size, fruit.color)
+		if isAutopilot {
+			drawAutopilotPath()
+		}
+
+		// Draw fruits to pick
+		for _, f := range fruits {
+			if f.active {
+				DrawRectangleV(f.position, f.size, f.color)
+			}
+		}
+
+		if isMultiplayer() {
+			drawPlayersHUD()
+		} else {
+			drawHUD()
+		}
 
 		if pause {
 			DrawText("GAME PAUSED", screenWidth/2-MeasureText("GAME PAUSED", 40)/2, screenHeight/2-40, 40, Gray)
 		}
+	} else if isMatchOver {
+		drawStandings()
+	} else if isMultiplayer() {
+		drawRoundOver()
 	} else {
-		drawText("PRESS [ENTER] TO PLAY AGAIN", GetScreenWidth()/2-measureText("PRESS [ENTER] TO PLAY AGAIN", 20)/2, GetScreenHeight()/2-50, 20, Gray)
-
+		drawGameOver()
 	}
 
 	EndDrawing()
 }
 
+// drawHUD draws the score, the length of the snake, the level and the high score at the top of the screen,
+// and the effects in place on the snake (see fruit.go).
+func drawHUD() {
+	snake := &snakes[0]
+
+	hud := fmt.Sprintf("SCORE: %d   LENGTH: %d   LEVEL: %d (%s)   HIGH SCORE: %d", snake.score, snake.body.len(), level, currentLevel().name, highScore)
+	drawText(hud, screenWidth-measureText(hud, 10)-10, 2, 10, DarkGray)
+
+	posX := 10
+	if isAutopilot {
+		drawText("AUTOPILOT", posX, 2, 10, Orange)
+		posX += measureText("AUTOPILOT", 10) + 10
+	}
+
+	drawEffects(snake, posX)
+}
+
+// drawGameOver draws the final score, and how it compares with the high score.
+func drawGameOver() {
+	title := "GAME OVER"
+	if isVictory {
+		title = "YOU WIN!"
+	}
+	drawText(title, screenWidth/2-measureText(title, 40)/2, screenHeight/2-150, 40, DarkGray)
+
+	result := fmt.Sprintf("SCORE: %d   LENGTH: %d   LEVEL: %d", snakes[0].score, snakes[0].body.len(), level)
+	drawText(result, screenWidth/2-measureText(result, 20)/2, screenHeight/2-90, 20, Gray)
+
+	record := fmt.Sprintf("HIGH SCORE: %d", highScore)
+	if isNewHighScore {
+		record = "NEW HIGH SCORE!"
+	}
+	drawText(record, screenWidth/2-measureText(record, 20)/2, screenHeight/2-65, 20, Maroon)
+
+	drawText("PRESS [ENTER] TO PLAY AGAIN", GetScreenWidth()/2-measureText("PRESS [ENTER] TO PLAY AGAIN", 20)/2, GetScreenHeight()/2-20, 20, Gray)
+}
+
 // Update and Draw (one frame)
 func UpdateDrawFrame() {
 	UpdateGame()
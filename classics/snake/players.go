@@ -0,0 +1,340 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	. "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ------------------------------------------------------------------------------------
+// Local multiplayer
+// ------------------------------------------------------------------------------------
+//
+// Up to maxPlayers snakes share the board, every one with its own colour and its own keys. The first snake
+// starts from the spawn of the level, the other ones from the opposite corners of the board (see spawnSquares).
+//
+// A snake dies if its head bumps into a wall, or into the body of any snake, its own one included (a ghost passes
+// through the bodies, see fruit.go). When two heads meet, on the same square or passing through each other,
+// the longest snake survives and the other one dies. If both are as long, both of them die. The body of a dead
+// snake leaves the board.
+//
+// A multiplayer match is made of roundsPerMatch rounds, all of them on the level chosen in the level select screen.
+// A round is over once there is only one snake left (or none), and the last one standing wins it. If the snakes
+// fill the board, the longest one wins the round. The scores add up from one round to the next, and the final
+// standings rank the players by rounds won, and then by score.
+
+const maxPlayers = 4
+
+// keySet are the keys that turn a snake.
+type keySet struct {
+	up, down, left, right int32
+}
+
+// playerKeys are the keys of every player: the arrows, WASD, IJKL and the numeric keypad.
+var playerKeys = [maxPlayers]keySet{
+	{up: KeyUp, down: KeyDown, left: KeyLeft, right: KeyRight},
+	{up: KeyW, down: KeyS, left: KeyA, right: KeyD},
+	{up: KeyI, down: KeyK, left: KeyJ, right: KeyL},
+	{up: KeyKp8, down: KeyKp5, left: KeyKp4, right: KeyKp6},
+}
+
+// playerNames tell the players apart in the HUD and in the standings, by the colour of their snake.
+var playerNames = [maxPlayers]string{"BLUE", "RED", "GREEN", "PURPLE"}
+
+// playerColors are the colours of the head and of the tail of every snake.
+var playerColors = [maxPlayers][2]Color{
+	{DarkBlue, Blue},
+	{Maroon, Red},
+	{DarkGreen, Lime},
+	{DarkPurple, Purple},
+}
+
+// newSnakes makes a snake for every player.
+func newSnakes() []Snake {
+	snakes := make([]Snake, playerCount)
+	for k := range snakes {
+		s := &snakes[k]
+
+		s.size = Vector2{X: float32(SQUARE_SIZE), Y: float32(SQUARE_SIZE)}
+		s.headColor = playerColors[k][0]
+		s.color = playerColors[k][1]
+		s.keys = playerKeys[k]
+		s.body.reset(Vector2{}, 1)
+	}
+
+	return snakes
+}
+
+// isMultiplayer reports whether there is more than one snake on the board.
+func isMultiplayer() bool {
+	return len(snakes) > 1
+}
+
+// spawnSquares returns where every snake starts: the spawn of the level for the first one, and the floor squares
+// closest to the other corners of the board (mirroring the spawn) for the other ones.
+func spawnSquares(count int) []square {
+	spawn := currentLevel().spawn
+	corners := [maxPlayers]square{
+		spawn,
+		{x: gridColumns - 1 - spawn.x, y: gridRows - 1 - spawn.y},
+		{x: gridColumns - 1 - spawn.x, y: spawn.y},
+		{x: spawn.x, y: gridRows - 1 - spawn.y},
+	}
+
+	spawns := []square{spawn}
+	for _, corner := range corners[1:count] {
+		best, bestDistance := spawn, gridColumns+gridRows
+		for x := 0; x < gridColumns; x++ {
+			for y := 0; y < gridRows; y++ {
+				distance := abs(x-corner.x) + abs(y-corner.y)
+				if board[x][y] != tileFloor || distance >= bestDistance || isSpawn(spawns, x, y) {
+					continue
+				}
+
+				best, bestDistance = square{x: x, y: y}, distance
+			}
+		}
+
+		spawns = append(spawns, best)
+	}
+
+	return spawns
+}
+
+// isSpawn reports whether a square is already one of the given spawns.
+func isSpawn(spawns []square, x, y int) bool {
+	for _, s := range spawns {
+		if s.x == x && s.y == y {
+			return true
+		}
+	}
+
+	return false
+}
+
+// abs returns the absolute value of x.
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
+
+// updateCollisions kills the snakes that have bumped into a wall, into a body, or into another head.
+// NOTE: All the snakes have moved before any of them dies, so two snakes running into each other both die.
+// Only a move can make a snake run into another one or bite: the bodies waiting on the spawns to unfold
+// (see loadLevel) don't count.
+func updateCollisions() {
+	dead := make([]bool, len(snakes))
+
+	// Wall behaviour: the edges of the board (unless in wrap-around mode) and the walls of the level (see levels.go)
+	for k := range snakes {
+		if x, y, ok := positionSquare(snakes[k].body.at(0)); snakes[k].isAlive && (!ok || board[x][y] == tileWall) {
+			dead[k] = true
+		}
+	}
+
+	// Head-on collisions: the longest snake wins
+	for a := range snakes {
+		for b := a + 1; b < len(snakes); b++ {
+			if !snakes[a].isAlive || !snakes[b].isAlive || !(snakes[a].hasMoved() || snakes[b].hasMoved()) {
+				continue
+			}
+
+			if !isHeadOn(&snakes[a], &snakes[b]) {
+				continue
+			}
+
+			lengthA, lengthB := snakes[a].body.len(), snakes[b].body.len()
+			dead[a] = dead[a] || lengthA <= lengthB
+			dead[b] = dead[b] || lengthB <= lengthA
+		}
+	}
+
+	killSnakes(dead)
+
+	// Collision with a body, unless the snake is a ghost (see fruit.go)
+	for k := range snakes {
+		s := &snakes[k]
+		if s.isAlive && s.hasMoved() && s.hasBitten() && !s.isEffectActive(effectGhost) {
+			dead[k] = true
+		}
+	}
+
+	killSnakes(dead)
+}
+
+// hasMoved reports whether the snake has moved on this frame (see framesPerMove).
+func (s *Snake) hasMoved() bool {
+	return s.moveCounter == 0
+}
+
+// isHeadOn reports whether the heads of two snakes have met: they are on the same square, or they have just
+// passed through each other.
+func isHeadOn(a, b *Snake) bool {
+	if a.body.at(0) == b.body.at(0) {
+		return true
+	}
+
+	return a.body.len() > 1 && b.body.len() > 1 && a.body.at(0) == b.body.at(1) && b.body.at(0) == a.body.at(1)
+}
+
+// killSnakes kills the given snakes (the ones still alive), and takes their bodies off the board.
+func killSnakes(dead []bool) {
+	for k := range snakes {
+		if dead[k] && snakes[k].isAlive {
+			snakes[k].isAlive = false
+			snakes[k].remove()
+		}
+	}
+}
+
+// countAliveSnakes returns the number of snakes still alive.
+func countAliveSnakes() int {
+	alive := 0
+	for _, s := range snakes {
+		if s.isAlive {
+			alive++
+		}
+	}
+
+	return alive
+}
+
+// endRound finds the winner of the round that has just ended: the last snake alive or, if the snakes have filled
+// the board, the longest one.
+func endRound() {
+	roundWinner = -1
+
+	longest := 0
+	for k, s := range snakes {
+		if !s.isAlive {
+			continue
+		}
+
+		switch {
+		case s.body.len() > longest:
+			roundWinner, longest = k, s.body.len()
+		case s.body.len() == longest:
+			roundWinner = -1 // A tie: nobody wins the round
+		}
+	}
+
+	if roundWinner >= 0 {
+		snakes[roundWinner].roundsWon++
+	}
+}
+
+// startRound puts the snakes back at their spawns, as short as at the start of the game, to play the next round.
+func startRound() {
+	gameOver = false
+	isVictory = false
+
+	for k := range snakes {
+		s := &snakes[k]
+
+		s.body.reset(Vector2{}, 1)
+		s.effectMoves = [effectCount]int{}
+	}
+
+	loadLevel(selectedLevel + 1)
+}
+
+// updateRoundOver waits for [ENTER] at the end of a round of a multiplayer match, to play the next round,
+// to show the final standings after the last round, and to go back to the level select screen after them.
+func updateRoundOver() {
+	if !IsKeyPressed(KeyEnter) {
+		return
+	}
+
+	switch {
+	case isMatchOver:
+		InitGame()
+		isSelectingLevel = true
+	case round >= roundsPerMatch:
+		isMatchOver = true
+	default:
+		round++
+		startRound()
+	}
+}
+
+// standings returns the players ranked by rounds won, and then by score.
+func standings() []int {
+	players := make([]int, len(snakes))
+	for k := range players {
+		players[k] = k
+	}
+
+	sort.SliceStable(players, func(i, j int) bool {
+		a, b := &snakes[players[i]], &snakes[players[j]]
+		if a.roundsWon != b.roundsWon {
+			return a.roundsWon > b.roundsWon
+		}
+
+		return a.score > b.score
+	})
+
+	return players
+}
+
+// drawPlayersHUD draws the score of every player (with the effects in place on its snake) and the round
+// at the top of the screen.
+func drawPlayersHUD() {
+	posX := 10
+	for k := range snakes {
+		s := &snakes[k]
+
+		text := fmt.Sprintf("%s: %d", playerNames[k], s.score)
+		if k == 0 && isAutopilot {
+			text += " (AUTOPILOT)"
+		}
+		if !s.isAlive {
+			text += " (OUT)"
+		}
+
+		drawText(text, posX, 2, 10, s.headColor)
+		posX = drawEffects(s, posX+measureText(text, 10)+10) + 10
+	}
+
+	hud := fmt.Sprintf("ROUND: %d/%d   LEVEL: %s", round, roundsPerMatch, currentLevel().name)
+	drawText(hud, screenWidth-measureText(hud, 10)-10, 2, 10, DarkGray)
+}
+
+// drawRoundOver draws who has won the round, and the rounds won by every player so far.
+func drawRoundOver() {
+	title := "DRAW!"
+	col := DarkGray
+	if roundWinner >= 0 {
+		title = playerNames[roundWinner] + " WINS THE ROUND!"
+		col = snakes[roundWinner].headColor
+	}
+	drawText(title, screenWidth/2-measureText(title, 40)/2, screenHeight/2-150, 40, col)
+
+	for k, s := range snakes {
+		result := fmt.Sprintf("%s   ROUNDS: %d   SCORE: %d", playerNames[k], s.roundsWon, s.score)
+		drawText(result, screenWidth/2-measureText(result, 20)/2, screenHeight/2-90+k*25, 20, s.headColor)
+	}
+
+	next := fmt.Sprintf("PRESS [ENTER] TO PLAY ROUND %d/%d", round+1, roundsPerMatch)
+	if round >= roundsPerMatch {
+		next = "PRESS [ENTER] TO SEE THE FINAL STANDINGS"
+	}
+	drawText(next, screenWidth/2-measureText(next, 20)/2, screenHeight/2+30, 20, Gray)
+}
+
+// drawStandings draws the final standings of the match.
+func drawStandings() {
+	drawText("FINAL STANDINGS", screenWidth/2-measureText("FINAL STANDINGS", 40)/2, screenHeight/2-150, 40, DarkGray)
+
+	for rank, k := range standings() {
+		s := &snakes[k]
+
+		result := fmt.Sprintf("%d. %s   ROUNDS: %d   SCORE: %d", rank+1, playerNames[k], s.roundsWon, s.score)
+		drawText(result, screenWidth/2-measureText(result, 20)/2, screenHeight/2-90+rank*25, 20, s.headColor)
+	}
+
+	drawText("PRESS [ENTER] TO PLAY AGAIN", screenWidth/2-measureText("PRESS [ENTER] TO PLAY AGAIN", 20)/2, screenHeight/2+30, 20, Gray)
+}
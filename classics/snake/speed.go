@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ------------------------------------------------------------------------------------
+// Speed curve
+// ------------------------------------------------------------------------------------
+//
+// The snake moves one square every few frames. The longer it gets, the fewer frames it waits between moves,
+// following a speed curve: it starts at startFrames, and waits one frame less every tailsPerStep squares
+// of tail, until it reaches minFrames. The FAST and SLOW fruits (see fruit.go) halve and double the frames
+// for a while, whatever the curve.
+
+// speedCurve describes how the speed of the snake rises with its length.
+type speedCurve struct {
+	startFrames  int // Frames between moves at the start of the game
+	minFrames    int // Frames between moves once the snake is at full speed
+	tailsPerStep int // Squares of tail the snake has to grow to move one frame faster
+}
+
+// speedCurves lists the available speed curves by the name used to select them (see the -speed flag).
+var speedCurves = map[string]speedCurve{
+	"fixed":  {startFrames: 5, minFrames: 5, tailsPerStep: 1}, // The original game: the same speed forever
+	"normal": {startFrames: 8, minFrames: 3, tailsPerStep: 4},
+	"hard":   {startFrames: 6, minFrames: 2, tailsPerStep: 2},
+}
+
+// findSpeedCurve returns the speed curve registered under the given name.
+func findSpeedCurve(name string) (speedCurve, error) {
+	curve, ok := speedCurves[name]
+	if !ok {
+		names := make([]string, 0, len(speedCurves))
+		for n := range speedCurves {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		return curve, fmt.Errorf("unknown speed curve %q (valid: %s)", name, strings.Join(names, ", "))
+	}
+
+	return curve, nil
+}
+
+// framesPerMove returns the number of frames the snake waits between two moves, at its current length.
+func (s *Snake) framesPerMove() int {
+	frames := speed.startFrames - (s.body.len()-1)/speed.tailsPerStep
+	if frames < speed.minFrames {
+		frames = speed.minFrames
+	}
+
+	switch {
+	case s.isEffectActive(effectFast):
+		frames = (frames + 1) / 2
+	case s.isEffectActive(effectSlow):
+		frames *= 2
+	}
+
+	return frames
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"math"
+
+	. "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ------------------------------------------------------------------------------------
+// Wrap-around and portals
+// ------------------------------------------------------------------------------------
+//
+// In wrap-around mode the edges of the board are not lethal: the head leaves the board on one side and comes back
+// on the opposite one. Portals (see levels.go) move the head to their partner as soon as it steps on them.
+// Either way only the head jumps: the tail follows the trail of positions the head leaves behind (see body.go),
+// so it goes through the edge or the portal by itself.
+
+// wrapPosition brings a position (in pixels) that has left the board back on the opposite side.
+func wrapPosition(position Vector2) Vector2 {
+	x := int(math.Round(float64(position.X-offset.X/2) / float64(SQUARE_SIZE)))
+	y := int(math.Round(float64(position.Y-offset.Y/2) / float64(SQUARE_SIZE)))
+
+	return squarePosition((x%gridColumns+gridColumns)%gridColumns, (y%gridRows+gridRows)%gridRows)
+}
+
+// teleport returns the position of the partner of the portal at the given position (in pixels),
+// or the same position if there is no portal there.
+func teleport(position Vector2) Vector2 {
+	x, y, ok := positionSquare(position)
+	if !ok || board[x][y] != tilePortal {
+		return position
+	}
+
+	for _, pair := range currentLevel().portals {
+		for k, portal := range pair {
+			if portal.x == x && portal.y == y {
+				partner := pair[1-k]
+				return squarePosition(partner.x, partner.y)
+			}
+		}
+	}
+
+	return position
+}
+
+// moveHead moves the head of the snake one square forward, through the edges in wrap-around mode,
+// and through the portals.
+func (s *Snake) moveHead() {
+	head := s.body.at(0)
+	head.X += s.speed.X
+	head.Y += s.speed.Y
+
+	if isWrapAround {
+		head = wrapPosition(head)
+	}
+
+	s.move(teleport(head))
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrapPosition(t *testing.T) {
+	tests := []struct {
+		name string
+		from square
+		want square
+	}{
+		{"inside the board", square{4, 3}, square{4, 3}},
+		{"left edge", square{-1, 3}, square{gridColumns - 1, 3}},
+		{"right edge", square{gridColumns, 3}, square{0, 3}},
+		{"top edge", square{4, -1}, square{4, gridRows - 1}},
+		{"bottom edge", square{4, gridRows}, square{4, 0}},
+		{"corner", square{-1, gridRows}, square{gridColumns - 1, 0}},
+	}
+
+	for _, tt := range tests {
+		got := wrapPosition(squarePosition(tt.from.x, tt.from.y))
+		if want := squarePosition(tt.want.x, tt.want.y); got != want {
+			t.Errorf("%s: wrapPosition() = %v, want %v", tt.name, got, want)
+		}
+	}
+}
+
+func TestTeleport(t *testing.T) {
+	savedLevels, savedLevel, savedBoard := levels, level, board
+	defer func() {
+		levels, level, board = savedLevels, savedLevel, savedBoard
+	}()
+
+	l, err := parseLevel([]byte(strings.Join(levelRows(map[square]byte{{5, 3}: 'S', {1, 1}: 'P', {9, 2}: 'P', {3, 4}: 'P', {2, 7}: 'P'}), "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	levels, level, board = []levelDef{l}, 1, l.tiles
+
+	tests := []struct {
+		name string
+		from square
+		want square
+	}{
+		{"first portal", square{1, 1}, square{9, 2}},
+		{"its partner", square{9, 2}, square{1, 1}},
+		{"second pair", square{2, 7}, square{3, 4}},
+		{"floor", square{5, 5}, square{5, 5}},
+		{"off the board", square{-1, 1}, square{-1, 1}},
+	}
+
+	for _, tt := range tests {
+		got := teleport(squarePosition(tt.from.x, tt.from.y))
+		if want := squarePosition(tt.want.x, tt.want.y); got != want {
+			t.Errorf("%s: teleport() = %v, want %v", tt.name, got, want)
+		}
+	}
+}
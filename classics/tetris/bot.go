@@ -0,0 +1,320 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ------------------------------------------------------------------------------------
+// Bot (autoplayer)
+// ------------------------------------------------------------------------------------
+//
+// When a new piece spawns, the bot looks at every placement it can reach (rotate first, then shift left or right,
+// then hard drop), and for each of them at every placement of the next piece. The resulting grids are scored
+// with a few classic heuristics, and the bot goes for the placement leading to the best one.
+// It plays by synthesizing the same keys a player would press (see input.go), one key per frame.
+
+// Heuristic weights, found by a genetic algorithm (Yiyuan Lee, "Tetris AI: The (Near) Perfect Bot").
+const (
+	weightHeight    = -0.510066 // Sum of the heights of all the columns
+	weightLines     = 0.760666  // Number of lines cleared
+	weightHoles     = -0.35663  // Number of empty squares with at least one square above them
+	weightBumpiness = -0.184483 // Sum of the height differences between neighbour columns
+	weightTopOut    = -1000.0   // Penalty for placements that top out
+)
+
+// botMaxFramesPerPiece is a safety net: if the bot can't reach its target (e.g. a rotation failed),
+// it drops the piece where it is after this many frames.
+const botMaxFramesPerPiece = 60
+
+// botBoard is a simplified copy of the grid the bot can play on: true for walls and locked squares.
+type botBoard [][]bool
+
+// placement is a place the bot can put a piece at: the rotation state and the position of its bounding box.
+type placement struct {
+	rotation rotationState
+	x, y     int
+}
+
+// newBotBoard copies the current grid, without the active piece.
+func newBotBoard() botBoard {
+	board := botBoard(newMatrix[bool](gridSizeX, gridSizeY))
+
+	for i := 0; i < gridSizeX; i++ {
+		for j := 0; j < gridSizeY; j++ {
+			board[i][j] = grid[i][j] != EMPTY && grid[i][j] != MOVING
+		}
+	}
+
+	return board
+}
+
+// fits reports whether the tetromino fits on the board at the given position.
+func (b botBoard) fits(kind tetromino, rotation rotationState, x, y int) bool {
+	for _, c := range tetrominoCells(kind, rotation) {
+		if x+c.x < 0 || x+c.x >= gridSizeX || y+c.y < 0 || y+c.y >= gridSizeY || b[x+c.x][y+c.y] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// rotate simulates a SRS rotation, and returns the position of the piece after the kick (if any) and
+// whether the rotation was possible.
+func (b botBoard) rotate(kind tetromino, from placement, direction rotationDirection) (placement, bool) {
+	to := from.rotation.rotate(direction)
+
+	for _, kick := range kickOffsets(kind, from.rotation, to) {
+		if b.fits(kind, to, from.x+kick.x, from.y+kick.y) {
+			return placement{rotation: to, x: from.x + kick.x, y: from.y + kick.y}, true
+		}
+	}
+
+	return from, false
+}
+
+// placements returns every placement reachable from the given start position by rotating once
+// (clockwise, 180° or counter-clockwise), then shifting, and then dropping the piece.
+func (b botBoard) placements(kind tetromino, start placement) []placement {
+	var result []placement
+
+	for direction := rotationDirection(0); direction < 4; direction++ {
+		rotated := start
+		if direction != 0 {
+			var ok bool
+			if rotated, ok = b.rotate(kind, start, direction); !ok {
+				continue
+			}
+		}
+
+		// Shift both ways until the piece hits something
+		for _, step := range [2]int{-1, 1} {
+			for x := rotated.x; b.fits(kind, rotated.rotation, x, rotated.y); x += step {
+				if step == 1 && x == rotated.x {
+					continue // Already added when shifting to the left
+				}
+
+				y := rotated.y
+				for b.fits(kind, rotated.rotation, x, y+1) {
+					y++
+				}
+
+				result = append(result, placement{rotation: rotated.rotation, x: x, y: y})
+			}
+		}
+	}
+
+	return result
+}
+
+// place returns a copy of the board with the piece locked at the given placement and the completed lines cleared,
+// and the number of lines cleared.
+func (b botBoard) place(kind tetromino, p placement) (botBoard, int) {
+	b = b.clone()
+
+	for _, c := range tetrominoCells(kind, p.rotation) {
+		b[p.x+c.x][p.y+c.y] = true
+	}
+
+	clearedLines := 0
+	for j := gridSizeY - 2; j >= 0; j-- {
+		isComplete := true
+		for i := 1; i < gridSizeX-1; i++ {
+			if !b[i][j] {
+				isComplete = false
+				break
+			}
+		}
+
+		if !isComplete {
+			continue
+		}
+
+		// Move everything above one line down, and check this line again
+		for j2 := j; j2 > 0; j2-- {
+			for i := 1; i < gridSizeX-1; i++ {
+				b[i][j2] = b[i][j2-1]
+			}
+		}
+
+		for i := 1; i < gridSizeX-1; i++ {
+			b[i][0] = false
+		}
+
+		clearedLines++
+		j++
+	}
+
+	return b, clearedLines
+}
+
+// clone returns a copy of the board.
+func (b botBoard) clone() botBoard {
+	board := make(botBoard, len(b))
+	for x := range b {
+		board[x] = append([]bool(nil), b[x]...)
+	}
+
+	return board
+}
+
+// evaluate scores the board with the heuristics, the higher the better.
+func (b botBoard) evaluate(clearedLines int) float64 {
+	playableRows := gridSizeY - 1 // The last row is the floor
+
+	var (
+		heights                = make([]int, gridSizeX)
+		aggregateHeight, holes int
+		bumpiness              int
+		isToppedOut            bool
+	)
+
+	for col := 1; col < gridSizeX-1; col++ {
+		for j := 0; j < playableRows; j++ {
+			if b[col][j] {
+				if heights[col] == 0 {
+					heights[col] = playableRows - j
+				}
+			} else if heights[col] > 0 {
+				holes++
+			}
+		}
+
+		aggregateHeight += heights[col]
+
+		if col > 1 {
+			bumpiness += int(math.Abs(float64(heights[col] - heights[col-1])))
+		}
+
+		// Same rule as the game: a locked square in the top two rows is a game over
+		if b[col][0] || b[col][1] {
+			isToppedOut = true
+		}
+	}
+
+	score := weightHeight*float64(aggregateHeight) +
+		weightLines*float64(clearedLines) +
+		weightHoles*float64(holes) +
+		weightBumpiness*float64(bumpiness)
+
+	if isToppedOut {
+		score += weightTopOut
+	}
+
+	return score
+}
+
+// planPlacement looks for the best placement of the active piece, looking one piece ahead.
+func planPlacement() placement {
+	board := newBotBoard()
+	current := placement{rotation: pieceRotation, x: piecePosX, y: piecePosY}
+
+	best, bestScore := current, math.Inf(-1)
+
+	for _, p := range board.placements(pieceKind, current) {
+		afterCurrent, clearedLines := board.place(pieceKind, p)
+		score := afterCurrent.evaluate(clearedLines)
+
+		// Look ahead: the placement is as good as the best grid the next piece can make out of it
+		if len(nextQueue) > 0 && nextQueue[0] != noTetromino {
+			next := nextQueue[0]
+			spawn := placement{rotation: rotation0, x: tetrominoSpawnX(next), y: 0}
+
+			if afterCurrent.fits(next, spawn.rotation, spawn.x, spawn.y) {
+				bestNext := math.Inf(-1)
+				for _, q := range afterCurrent.placements(next, spawn) {
+					afterNext, nextClearedLines := afterCurrent.place(next, q)
+					bestNext = math.Max(bestNext, afterNext.evaluate(clearedLines+nextClearedLines))
+				}
+
+				score = bestNext
+			} else {
+				score += weightTopOut
+			}
+		}
+
+		if score > bestScore {
+			best, bestScore = p, score
+		}
+	}
+
+	return best
+}
+
+// botInput returns the keys the bot presses during this frame.
+func botInput() inputState {
+	var state inputState
+
+	if isSelectingMode || isGameover || isPaused || hasLineToDelete || !isPieceFalling {
+		return state
+	}
+
+	// Plan once per piece
+	if botPlannedPiece != spawnCounter {
+		botTarget = planPlacement()
+		botPlannedPiece = spawnCounter
+		botFrames = 0
+	}
+
+	botFrames++
+
+	// One key per frame: first rotate, then shift, and finally hard drop
+	switch {
+	case botFrames > botMaxFramesPerPiece:
+		state.press(rl.KeySpace)
+	case pieceRotation != botTarget.rotation:
+		switch (botTarget.rotation - pieceRotation + 4) % 4 {
+		case rotationState(rotateClockwise):
+			state.press(rl.KeyUp)
+		case rotationState(rotate180):
+			state.press(rl.KeyA)
+		default:
+			state.press(rl.KeyZ)
+		}
+	case piecePosX > botTarget.x:
+		state.press(rl.KeyLeft)
+	case piecePosX < botTarget.x:
+		state.press(rl.KeyRight)
+	default:
+		state.press(rl.KeySpace)
+	}
+
+	return state
+}
+
+// runHeadless plays the given number of games with the bot, without opening a window, and prints the results.
+func runHeadless(games int) {
+	rl.SetRandomSeed(uint32(time.Now().UnixNano()))
+
+	var totalLines, totalScore, totalPieces, completed int
+
+	for g := 1; g <= games; g++ {
+		reset()
+		currentMode = selectedMode
+		isSelectingMode = false
+		isGameover = false
+
+		for !isGameover {
+			input = botInput()
+			UpdateGame()
+		}
+
+		if gameOverResult == resultCompleted {
+			completed++
+		}
+
+		totalLines += lines
+		totalScore += score
+		totalPieces += spawnCounter
+
+		fmt.Printf("game %d: %d lines, %d points, %d pieces\n", g, lines, score, spawnCounter)
+	}
+
+	fmt.Printf("\n%s, %d games: %d completed, %.2f lines, %.2f points and %.2f pieces on average\n",
+		gameModes[selectedMode].name, games, completed,
+		float64(totalLines)/float64(games), float64(totalScore)/float64(games), float64(totalPieces)/float64(games))
+}
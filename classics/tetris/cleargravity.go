@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ------------------------------------------------------------------------------------
+// Line clear gravity
+// ------------------------------------------------------------------------------------
+//
+// Once the completed lines are deleted, the squares above them have to fall. With the classic (naive) gravity
+// every row above a deleted line moves down one row, even if that leaves squares floating in the air.
+// With sticky and cascade gravity the squares are grouped into chunks instead, and every chunk falls on its own
+// until it lands. A chunk landing may complete new lines: they fade and are deleted like the first ones, and
+// so on until nothing falls anymore. Every step of such a chain scores more than the previous one.
+//
+// NOTE: In cascade mode the squares are grouped by the piece they come from (see gridPiece), so two pieces of
+//       the same kind touching each other fall on their own. The garbage squares all stick together.
+
+type clearGravity int
+
+const (
+	clearGravityNaive   clearGravity = iota // The rows above the deleted lines move down as a whole
+	clearGravitySticky                      // Connected squares stick together, and fall as a chunk
+	clearGravityCascade                     // Connected squares of the same piece stick together, and fall as a chunk
+)
+
+// clearGravities lists the available line clear gravities by the name used to select them (see the -gravity flag).
+var clearGravities = map[string]clearGravity{
+	"naive":   clearGravityNaive,
+	"sticky":  clearGravitySticky,
+	"cascade": clearGravityCascade,
+}
+
+// parseClearGravity returns the line clear gravity registered under the given name.
+func parseClearGravity(name string) (clearGravity, error) {
+	gravity, ok := clearGravities[name]
+	if !ok {
+		names := make([]string, 0, len(clearGravities))
+		for n := range clearGravities {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		return 0, fmt.Errorf("unknown line clear gravity %q (valid: %s)", name, strings.Join(names, ", "))
+	}
+
+	return gravity, nil
+}
+
+// clearGravityName returns the name of the given line clear gravity (see clearGravities).
+func clearGravityName(gravity clearGravity) string {
+	for name, g := range clearGravities {
+		if g == gravity {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// deleteLines deletes the completed (FADING) lines, and lets the squares above them fall following the line
+// clear gravity. If the fall completes new lines, they are marked (FADING) and scored as the next step of the chain.
+func deleteLines() {
+	if lineClearGravity == clearGravityNaive {
+		DeleteCompleteLines()
+		return
+	}
+
+	for j := 0; j < gridSizeY-1; j++ {
+		for i := 1; i < gridSizeX-1; i++ {
+			if grid[i][j] == FADING {
+				grid[i][j] = EMPTY
+			}
+		}
+	}
+
+	dropChunks()
+
+	CheckCompletion(&hasLineToDelete)
+
+	if clearedLines := countFadingLines(); clearedLines > 0 {
+		chain++
+		scoreChain(clearedLines)
+		countClearedLines(clearedLines, tSpinNone)
+	}
+}
+
+// dropChunks lets every chunk of squares fall until it lands on the floor or on another chunk.
+// NOTE: The chunks fall one row at a time, and are found again after every step, as landing chunks may stick together.
+func dropChunks() {
+	for hasFallen := true; hasFallen; {
+		hasFallen = false
+
+		for _, chunk := range findChunks() {
+			if canChunkFall(chunk) {
+				moveChunkDown(chunk)
+				hasFallen = true
+			}
+		}
+	}
+}
+
+// findChunks groups the FULL squares of the grid into chunks of squares sticking together (see clearGravity).
+func findChunks() [][]cell {
+	var chunks [][]cell
+
+	visited := newMatrix[bool](gridSizeX, gridSizeY)
+
+	for i := 1; i < gridSizeX-1; i++ {
+		for j := 0; j < gridSizeY-1; j++ {
+			if grid[i][j] != FULL || visited[i][j] {
+				continue
+			}
+
+			// Flood fill from this square
+			visited[i][j] = true
+			chunk := []cell{{x: i, y: j}}
+
+			for k := 0; k < len(chunk); k++ {
+				c := chunk[k]
+
+				for _, offset := range [4]cell{{-1, 0}, {+1, 0}, {0, -1}, {0, +1}} {
+					x, y := c.x+offset.x, c.y+offset.y
+					if grid[x][y] != FULL || visited[x][y] {
+						continue
+					}
+
+					if lineClearGravity == clearGravityCascade && gridPiece[x][y] != gridPiece[c.x][c.y] {
+						continue
+					}
+
+					visited[x][y] = true
+					chunk = append(chunk, cell{x: x, y: y})
+				}
+			}
+
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	return chunks
+}
+
+// canChunkFall reports whether every square below the chunk is empty (or part of the chunk itself).
+func canChunkFall(chunk []cell) bool {
+	for _, c := range chunk {
+		if grid[c.x][c.y+1] == EMPTY {
+			continue
+		}
+
+		isOwnSquare := false
+		for _, other := range chunk {
+			if other.x == c.x && other.y == c.y+1 {
+				isOwnSquare = true
+				break
+			}
+		}
+
+		if !isOwnSquare {
+			return false
+		}
+	}
+
+	return true
+}
+
+// moveChunkDown moves the squares of the chunk one row down, keeping their colour and the piece they come from.
+func moveChunkDown(chunk []cell) {
+	kinds := make([]tetromino, len(chunk))
+	pieces := make([]int, len(chunk))
+	for k, c := range chunk {
+		kinds[k] = gridKind[c.x][c.y]
+		pieces[k] = gridPiece[c.x][c.y]
+		grid[c.x][c.y] = EMPTY
+	}
+
+	for k, c := range chunk {
+		grid[c.x][c.y+1] = FULL
+		gridKind[c.x][c.y+1] = kinds[k]
+		gridPiece[c.x][c.y+1] = pieces[k]
+	}
+}
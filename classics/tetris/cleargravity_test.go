@@ -0,0 +1,148 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// loadChunkBoard empties the grid and lays the given rows at its bottom: '.' is an empty square,
+// and a digit a square of the locked piece with that number (see gridPiece).
+func loadChunkBoard(rows []string) {
+	clearGrid()
+
+	top := gridSizeY - 1 - len(rows)
+	for y, row := range rows {
+		for x, square := range row {
+			if square != '.' {
+				grid[x+1][top+y] = FULL
+				gridPiece[x+1][top+y] = int(square - '0')
+			}
+		}
+	}
+}
+
+// chunkBoardRows returns the given number of rows at the bottom of the grid, the way loadChunkBoard reads them.
+func chunkBoardRows(n int) []string {
+	rows := make([]string, n)
+
+	top := gridSizeY - 1 - n
+	for y := range rows {
+		for x := 1; x < gridSizeX-1; x++ {
+			square := byte('.')
+			if grid[x][top+y] == FULL {
+				square = byte('0' + gridPiece[x][top+y])
+			}
+
+			rows[y] += string(square)
+		}
+	}
+
+	return rows
+}
+
+func TestFindChunks(t *testing.T) {
+	tests := []struct {
+		name    string
+		gravity clearGravity
+		rows    []string
+		want    []int // Sizes of the chunks
+	}{
+		{"apart", clearGravitySticky, []string{"11..2.....", "11..2....."}, []int{2, 4}},
+		{"touching", clearGravitySticky, []string{"1122......", "1.2......."}, []int{6}},
+		{"touching pieces", clearGravityCascade, []string{"1122......", "1.2......."}, []int{3, 3}},
+		{"diagonal", clearGravitySticky, []string{"1.........", ".1........"}, []int{1, 1}},
+		{"garbage", clearGravityCascade, []string{"00.000000.", "0000.0000."}, []int{16}},
+	}
+
+	for _, tt := range tests {
+		lineClearGravity = tt.gravity
+		loadChunkBoard(tt.rows)
+
+		var got []int
+		for _, chunk := range findChunks() {
+			got = append(got, len(chunk))
+		}
+		sort.Ints(got)
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: chunks of %v squares, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	lineClearGravity = clearGravityNaive
+}
+
+func TestCanChunkFall(t *testing.T) {
+	loadChunkBoard([]string{
+		"1.........",
+		"1...2.....",
+		"4...2...3.",
+		"33......3.",
+	})
+
+	floor := gridSizeY - 2
+	tests := []struct {
+		name  string
+		chunk []cell
+		want  bool
+	}{
+		{"on the floor", []cell{{1, floor}, {2, floor}}, false},
+		{"in the air, over its own square", []cell{{5, floor - 2}, {5, floor - 1}}, true},
+		{"on another chunk", []cell{{1, floor - 3}, {1, floor - 2}}, false},
+		{"standing on the floor", []cell{{9, floor - 1}, {9, floor}}, false},
+	}
+
+	for _, tt := range tests {
+		if got := canChunkFall(tt.chunk); got != tt.want {
+			t.Errorf("%s: canChunkFall() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDropChunks(t *testing.T) {
+	tests := []struct {
+		name    string
+		gravity clearGravity
+		rows    []string
+		want    []string
+	}{
+		{
+			name:    "floating chunk",
+			gravity: clearGravitySticky,
+			rows:    []string{"11........", "..........", "....2....."},
+			want:    []string{"..........", "..........", "11..2....."},
+		},
+		{
+			name:    "stacked chunks",
+			gravity: clearGravitySticky,
+			rows:    []string{"..3.......", "..........", "..2.......", ".........."},
+			want:    []string{"..........", "..........", "..3.......", "..2......."},
+		},
+		{
+			name:    "touching pieces stick together",
+			gravity: clearGravitySticky,
+			rows:    []string{"1.........", "12........", ".2........", ".........."},
+			want:    []string{"..........", "1.........", "12........", ".2........"},
+		},
+		{
+			name:    "touching pieces fall on their own",
+			gravity: clearGravityCascade,
+			rows:    []string{"1.........", "12........", ".2........", ".........."},
+			want:    []string{"..........", "..........", "12........", "12........"},
+		},
+	}
+
+	for _, tt := range tests {
+		lineClearGravity = tt.gravity
+		loadChunkBoard(tt.rows)
+
+		dropChunks()
+
+		if got := chunkBoardRows(len(tt.rows)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: dropChunks() leaves %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	lineClearGravity = clearGravityNaive
+}
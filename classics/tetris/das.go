@@ -0,0 +1,90 @@
+package main
+
+import rl "github.com/gen2brain/raylib-go/raylib"
+
+// ------------------------------------------------------------------------------------
+// Handling: Delayed Auto Shift (DAS), Auto Repeat Rate (ARR) and Soft Drop Factor (SDF)
+// ------------------------------------------------------------------------------------
+//
+// Tapping left or right moves the piece one cell. Holding the key moves it once, waits for DAS frames,
+// and then keeps moving it one cell every ARR frames (or all the way to the wall at once when ARR is 0).
+
+// handling holds the player's preferences on how the piece responds to the keys.
+type handling struct {
+	das int // Frames the left/right key must be held before the piece starts auto-shifting.
+	arr int // Frames between two auto-shift steps, 0 moves the piece to the wall at once.
+	sdf int // How many times faster than gravity the piece falls while the down key is held.
+}
+
+// defaultHandling is a comfortable setup for most players.
+var defaultHandling = handling{das: 10, arr: 2, sdf: 20}
+
+// updateAutoShift moves the piece laterally at player's will, following the DAS/ARR rules.
+func updateAutoShift() {
+	// When both keys are held, the last one pressed wins
+	direction := 0
+	switch {
+	case isKeyPressed(rl.KeyLeft):
+		direction = -1
+	case isKeyPressed(rl.KeyRight):
+		direction = 1
+	case shiftDirection == -1 && isKeyDown(rl.KeyLeft), shiftDirection == 1 && isKeyDown(rl.KeyRight):
+		direction = shiftDirection
+	case isKeyDown(rl.KeyLeft):
+		direction = -1
+	case isKeyDown(rl.KeyRight):
+		direction = 1
+	}
+
+	// A new direction (or a new press) moves the piece once, and starts charging the DAS
+	if direction != shiftDirection || isKeyPressed(rl.KeyLeft) || isKeyPressed(rl.KeyRight) {
+		shiftDirection = direction
+		dasCounter = 0
+		arrCounter = 0
+
+		if direction != 0 {
+			ResolveLateralMovement(direction)
+		}
+
+		return
+	}
+
+	if direction == 0 {
+		return
+	}
+
+	// Wait until the DAS is charged
+	if dasCounter < playerHandling.das {
+		dasCounter++
+		if dasCounter < playerHandling.das {
+			return
+		}
+
+		arrCounter = playerHandling.arr // Auto-shift right away once the DAS is charged
+	} else {
+		arrCounter++
+	}
+
+	if playerHandling.arr == 0 {
+		// Move until the piece hits something
+		for !ResolveLateralMovement(direction) {
+		}
+
+		return
+	}
+
+	if arrCounter >= playerHandling.arr {
+		arrCounter = 0
+		ResolveLateralMovement(direction)
+	}
+}
+
+// gravityStep returns how much the verticalMoveCounter advances during this frame:
+// one frame, or SDF frames while the player is soft dropping.
+func gravityStep(isSoftDropping bool) int {
+	if isSoftDropping {
+		return playerHandling.sdf
+	}
+
+	return 1
+}
@@ -0,0 +1,98 @@
+package main
+
+import rl "github.com/gen2brain/raylib-go/raylib"
+
+// ------------------------------------------------------------------------------------
+// Input
+// ------------------------------------------------------------------------------------
+//
+// The game logic never asks raylib about the keyboard directly: the keys it cares about (polledKeys) are
+// polled once at the beginning of every frame into an inputState, and UpdateGame reads that instead.
+// This way the input can also be synthesized (e.g. by the bot, see bot.go) instead of typed by a player.
+
+// polledKeys are the keys the game logic reads. Their position in the list is their bit in an inputState.
+var polledKeys = [...]int32{
+	rl.KeyLeft,      // Move left
+	rl.KeyRight,     // Move right
+	rl.KeyUp,        // Rotate clockwise (and choose the game mode)
+	rl.KeyDown,      // Soft drop (and choose the game mode)
+	rl.KeyP,         // Pause
+	rl.KeyX,         // Rotate clockwise
+	rl.KeyZ,         // Rotate counter-clockwise
+	rl.KeyA,         // Rotate 180°
+	rl.KeySpace,     // Hard drop
+	rl.KeyC,         // Hold
+	rl.KeyLeftShift, // Hold
+	rl.KeyEnter,     // Start a game
+	rl.KeyBackspace, // End a Zen session
+}
+
+// inputState holds the state of the polled keys during one frame.
+type inputState struct {
+	down    uint32 // One bit per polled key, set while the key is held
+	pressed uint32 // One bit per polled key, set only on the frame the key goes down
+}
+
+// keyBit returns the bit of the given key in an inputState, or 0 if the key is not polled.
+func keyBit(key int32) uint32 {
+	for k, polled := range polledKeys {
+		if polled == key {
+			return 1 << k
+		}
+	}
+
+	return 0
+}
+
+// keyMap tells which key of the keyboard stands for every polled key (e.g. [W] for [UP] in versus mode).
+type keyMap map[int32]int32
+
+// pollKeyboard reads the state of the polled keys from the keyboard.
+func pollKeyboard() inputState {
+	var state inputState
+
+	for _, key := range polledKeys {
+		state.poll(key, key)
+	}
+
+	return state
+}
+
+// pollKeyMap reads the state of the polled keys from the keyboard, through the given key map.
+// The polled keys missing from the map are never held nor pressed.
+func pollKeyMap(keys keyMap) inputState {
+	var state inputState
+
+	for key, physical := range keys {
+		state.poll(key, physical)
+	}
+
+	return state
+}
+
+// poll sets the given polled key as held or pressed, if the physical key of the keyboard is.
+func (s *inputState) poll(key, physical int32) {
+	if rl.IsKeyDown(physical) {
+		s.down |= keyBit(key)
+	}
+
+	if rl.IsKeyPressed(physical) {
+		s.pressed |= keyBit(key)
+	}
+}
+
+// press marks the given key as pressed (and held) in this frame.
+func (s *inputState) press(key int32) {
+	s.down |= keyBit(key)
+	s.pressed |= keyBit(key)
+}
+
+// isKeyDown is the same as rl.IsKeyDown, but reads the input of the current frame.
+func isKeyDown(key int32) bool {
+	return input.down&keyBit(key) != 0
+}
+
+// isKeyPressed is the same as rl.IsKeyPressed, but reads the input of the current frame.
+func isKeyPressed(key int32) bool {
+	return input.pressed&keyBit(key) != 0
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ------------------------------------------------------------------------------------
+// Lock delay
+// ------------------------------------------------------------------------------------
+//
+// Once the piece touches the ground (or another piece) it doesn't lock right away: the player has a few frames
+// (lockDelayFrames) to slide it under an overhang or rotate it into place. The lock reset mode decides which
+// actions give the player a fresh lock delay.
+
+const maxLockResets = 15 // Number of moves/rotations that reset the lock delay in lockResetMove mode
+
+type lockResetMode int
+
+const (
+	lockResetInfinity lockResetMode = iota // Every move or rotation resets the lock delay, forever
+	lockResetMove                          // Moves and rotations on the ground reset the lock delay, up to maxLockResets times per row
+	lockResetStep                          // Only falling one row down resets the lock delay
+)
+
+// lockResetModes lists the available lock reset modes by the name used to select them (see the -lockreset flag).
+var lockResetModes = map[string]lockResetMode{
+	"infinity": lockResetInfinity,
+	"move":     lockResetMove,
+	"step":     lockResetStep,
+}
+
+// parseLockResetMode returns the lock reset mode registered under the given name.
+func parseLockResetMode(name string) (lockResetMode, error) {
+	mode, ok := lockResetModes[name]
+	if !ok {
+		names := make([]string, 0, len(lockResetModes))
+		for n := range lockResetModes {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		return 0, fmt.Errorf("unknown lock reset mode %q (valid: %s)", name, strings.Join(names, ", "))
+	}
+
+	return mode, nil
+}
+
+// resetLockDelay starts the lock delay of the active piece from scratch.
+func resetLockDelay() {
+	lockDelayCounter = 0
+	lockResets = 0
+	lowestRow = piecePosY
+}
+
+// onPieceStepDown must be called every time the active piece falls one row down.
+// It resets the lock delay in every mode, and gives back all the resets once the piece reaches a new lowest row.
+func onPieceStepDown() {
+	lockDelayCounter = 0
+
+	if piecePosY > lowestRow {
+		lowestRow = piecePosY
+		lockResets = 0
+	}
+}
+
+// onPieceMoved must be called every time the player successfully moves or rotates the active piece.
+func onPieceMoved() {
+	switch lockReset {
+	case lockResetInfinity:
+		lockDelayCounter = 0
+	case lockResetMove:
+		// Only the moves that leave the piece on the ground use up a reset: the piece can be moved freely while it falls
+		// NOTE: isDownCollided is only updated when the piece falls (see 6b.4), it may be stale after sliding off a ledge
+		if !checkCollisionY() {
+			break
+		}
+
+		if lockResets < maxLockResets {
+			lockDelayCounter = 0
+		}
+
+		lockResets++
+	case lockResetStep:
+		// Moving or rotating doesn't give the player any extra time
+	}
+}
+
+// updateLockDelay counts down the lock delay while the active piece is on the ground,
+// and locks it once the delay has expired.
+func updateLockDelay() {
+	if !checkCollisionY() {
+		return
+	}
+
+	lockDelayCounter++
+
+	// In move-reset mode, once the player has run out of resets the piece locks as soon as it touches the ground.
+	outOfResets := lockReset == lockResetMove && lockResets >= maxLockResets
+
+	if lockDelayCounter >= lockDelayFrames || outOfResets {
+		lockPiece()
+	}
+}
@@ -14,8 +14,10 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"image/color"
+	"os"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 	"golang.org/x/exp/constraints"
@@ -25,18 +27,27 @@ import (
 // Some Defines
 // ----------------------------------------------------------------------------------
 const (
-	squareSize           = 20 // Size of the squares that compose the pieces
-	gridSizeX            = 12 // 10 + 2 (left and right walls)
-	gridSizeY            = 20 // 18 + 2 (top and bottom walls)
 	speedTurn            = 12
 	fastFallAwaitCounter = 30
 	timeToFade           = 33
 
-	// Deceleration: the higher the value, the slower the piece moves
-	// It is the number of frames that must pass before the piece moves down one cell
-	// NOTE: The higher the value, the slower the piece moves (60 frames --> 1 second).
-	framesToWaitBeforeMoveDown        = 30
-	framesToWaitBeforeLateralMovement = 10
+	// NOTE: The number of frames that must pass before the piece moves down one cell depends on the level,
+	//       see gravityTable in scoring.go, and the lateral movement depends on the player's handling, see das.go.
+
+	maxPreviewCount = 6 // Maximum number of incoming pieces shown in the NEXT queue
+
+	// Limits of the board size (playable area, without the walls), see the -width and -height flags
+	minBoardWidth  = 4
+	maxBoardWidth  = 30
+	minBoardHeight = 8
+	maxBoardHeight = 40
+)
+
+// Size of the grid, and of its squares on the screen. They are set at startup (see the -width and -height flags).
+var (
+	squareSize = 20 // Size of the squares that compose the pieces (in pixels), smaller on big boards to fit the screen
+	gridSizeX  = 12 // 10 + 2 (left and right walls)
+	gridSizeY  = 20 // 18 + 2 (top and bottom walls)
 )
 
 //----------------------------------------------------------------------------------
@@ -71,42 +82,273 @@ var (
 	isPieceFalling  bool // used to know if a piece is active or not.
 	isDownCollided  bool // has a piece reached the bottom of the grid or another piece.
 	hasLineToDelete bool // used to know if a line has to be deleted.
+	isSelectingMode bool // is the player choosing the game mode? (before the game starts)
+
+	// Game mode (see modes.go)
+	selectedMode   gameMode   // Game mode highlighted in the mode selector.
+	currentMode    gameMode   // Game mode being played.
+	gameOverResult gameResult // How the last game has ended.
+	framesCounter  int        // Number of frames played (used as the game clock).
+
+	// Handling (see das.go)
+	playerHandling handling // How the piece responds to the keys of the player of this board (see handlings).
 
 	// Counters
-	verticalMoveCounter   int // Counter used to move the piece down.
-	horizontalMoveCounter int // Counter used to move the piece left or right
-	turnMovementCounter   int // Counter used to turn the piece.
-	fastFallMoveCounter   int // Counter used to move the piece down faster.
-	fadeLineCounter       int // Counter used to fade a line.
-
-	// The tetrominos (pieces, bricks, blocks, whatever you want to call them) are stored in a 4x4 matrix.
-	piece         [4][4]gridSquare // geometric shape, composed of 4 squares connected orthogonally.
-	incomingPiece [4][4]gridSquare // Next tetromino to be created (non-active piece).
-	piecePosX     int              // X position of the current active tetromino (in grid squares, not pixels).
-	piecePosY     int              // Y position of the current active tetromino (in grid squares, not pixels).
+	verticalMoveCounter int // Counter used to move the piece down.
+	dasCounter          int // Counter used to charge the Delayed Auto Shift while left or right is held.
+	arrCounter          int // Counter used to auto-shift the piece left or right once the DAS is charged.
+	shiftDirection      int // Direction the player is moving the piece to: -1 left, 1 right, 0 none.
+	turnMovementCounter int // Counter used to turn the piece.
+	fastFallMoveCounter int // Counter used to move the piece down faster.
+	fadeLineCounter     int // Counter used to fade a line.
+	lockDelayCounter    int // Counter used to lock the piece once it has been on the ground for long enough.
+	lockResets          int // Number of times the lock delay has been reset by moving or rotating the piece.
+	lowestRow           int // Lowest row reached by the active piece (used to give back the lock resets).
+
+	// The tetrominos (pieces, bricks, blocks, whatever you want to call them) are stored in a square matrix,
+	// as big as their bounding box (4x4 for the I tetromino).
+	piece         [][]gridSquare // geometric shape, composed of squares (4 for a tetromino) connected orthogonally.
+	piecePosX     int            // X position of the current active tetromino (in grid squares, not pixels).
+	piecePosY     int            // Y position of the current active tetromino (in grid squares, not pixels).
+	pieceKind     tetromino      // Which one of the pieces of the set (see pieceSet) is the active piece.
+	pieceRotation rotationState  // SRS rotation state of the active piece.
+
+	// Hold slot
+	holdKind  tetromino // Tetromino stashed away by the player.
+	isHolding bool      // is there a tetromino in the hold slot?
+	canHold   bool      // can the player still hold? (only once per piece, until it locks)
+
+	// Incoming pieces
+	pieceRandomizer randomizer  // Decides the sequence of tetrominoes (see randomizer.go).
+	nextQueue       []tetromino // Next tetrominoes to be created (non-active pieces), in order.
+	spawnCounter    int         // Number of pieces spawned since the game started.
+
+	// Input (see input.go)
+	input inputState // Keys held and pressed during the current frame, by the player or by the bot.
+
+	// Bot (see bot.go)
+	isBotPlaying    bool      // is the bot playing instead of the player? (toggled with [B])
+	botTarget       placement // Where the bot wants to put the active piece.
+	botPlannedPiece int       // Value of spawnCounter when the bot planned botTarget.
+	botFrames       int       // Number of frames the bot has spent on the active piece.
+
+	// Settings (see the command line flags in main)
+	randomizerName   = "bag7"            // Name of the randomizer to use.
+	previewCount     = 3                 // Number of incoming pieces shown in the NEXT queue (1 to maxPreviewCount).
+	lockDelayFrames  = 30                // Number of frames a piece can stay on the ground before it locks.
+	lockReset        = lockResetMove     // Which actions reset the lock delay (see lockdelay.go).
+	lineClearGravity = clearGravityNaive // How the squares fall once the completed lines are deleted (see cleargravity.go).
+
+	// How the piece responds to the keys of each player (see das.go), player 2 only plays in versus mode.
+	handlings = [2]handling{defaultHandling, defaultHandling}
+
+	// Piece set (see pieces.go)
+	pieceSetName       = standardPieceSetName // Name of the piece set (or path to its data file).
+	pieceSet           = tetrominoSpawn[:]    // Pieces the game is played with.
+	isStandardPieceSet = true                 // is the game played with the seven tetrominoes?
 
 	// Statistics
-	score int
+	score        int  // Points (see scoring.go).
+	lines        int  // Number of lines cleared.
+	level        int  // Current level, it rises every linesPerLevel lines and makes the pieces fall faster.
+	combo        int  // Number of consecutive pieces that cleared lines, minus one (-1 means no combo).
+	isBackToBack bool // was the last line clear a difficult one (e.g. a tetris)?
+	chain        int  // Number of line clears caused by the squares falling after the last one (see cleargravity.go).
+
+	// T-spins (see tspin.go)
+	isLastMoveRotation bool   // was the last successful move of the active piece a rotation?
+	lastKickIndex      int    // Which one of the SRS kick tests was used by the last rotation.
+	isLastRotation180  bool   // was the last rotation a 180° one? (its kick tests are not the 90° ones)
+	spinLabel          string // Name of the last T-spin, flashed next to the grid.
+	spinLabelCounter   int    // Counter used to flash the T-spin label.
+
+	// Garbage (see versus.go)
+	pendingGarbage  int // Garbage rows sent by the opponent, waiting to rise from the bottom of the grid.
+	outgoingGarbage int // Garbage rows to be sent to the opponent at the end of the frame.
+	sentGarbage     int // Number of garbage rows sent to the opponent since the game started.
+
+	// Versus mode (see versus.go)
+	versusBoards [2]boardState // State of the board of each player, while the other one is being played.
+	versusWinner int           // Player who won the versus game, or noWinner while it's being played.
+
+	// Puzzles (see puzzle.go)
+	puzzles             []puzzle // Puzzles the player can choose from.
+	puzzleIndex         int      // Puzzle chosen in the mode selector.
+	puzzleLines         int      // Lines cleared that count for the objective of the puzzle.
+	isPerfectClear      bool     // has the last piece locked left the board empty?
+	finesseInputs       int      // Keys pressed to move and rotate the active piece.
+	hasSoftDropped      bool     // has the active piece been soft dropped? (its finesse is not judged)
+	finesseFaults       int      // Inputs wasted since the puzzle started.
+	finesseLabel        string   // Finesse verdict on the last piece locked, flashed next to the grid.
+	finesseLabelCounter int      // Counter used to flash the finesse verdict.
+
+	// Replays (see replay.go)
+	recordPath       string // File the games are recorded to (the last game overwrites the previous one), if any.
+	isRecording      bool   // is the game being recorded?
+	recording        replay // Game being recorded.
+	isReplaying      bool   // is the player watching a replay?
+	playback         replay // Replay being watched.
+	playbackFrames   [2]int // Number of frames of the replay played so far, for every player.
+	playbackSpeed    int    // Frames of the replay played per frame drawn (fast-forward).
+	isPlaybackPaused bool   // is the replay paused?
 
 	// Grid
-	grid [gridSizeX][gridSizeY]gridSquare // Grid area matrix.
-
-	// Colors
-	fadingColor rl.Color
+	grid         [][]gridSquare // Grid area matrix (gridSizeX x gridSizeY).
+	gridKind     [][]tetromino  // Tetromino every FULL (or FADING) square of the grid comes from.
+	gridPiece    [][]int        // Locked piece every FULL (or FADING) square of the grid comes from (0 for garbage).
+	lockedPieces int            // Number of pieces locked so far, which numbers them in gridPiece.
+
+	// Colors (see skins.go)
+	skinIndex       int  // Skin in use, the player can switch to the next one with [TAB].
+	isFadingFlashOn bool // are the completed lines highlighted? (they flash before being deleted)
 )
 
-// init initializes the game the first time.
-func init() {
-	reset()
-}
-
 // ------------------------------------------------------------------------------------
 // Program main entry point
 // ------------------------------------------------------------------------------------
 func main() {
+	flag.StringVar(&randomizerName, "randomizer", randomizerName, "piece randomizer: bag7, bag14, classic or tgm")
+	skinName := flag.String("skin", currentSkin().name, "initial skin: guideline, monochrome or contrast")
+	flag.IntVar(&previewCount, "preview", previewCount, fmt.Sprintf("number of incoming pieces shown (1-%d)", maxPreviewCount))
+	flag.IntVar(&lockDelayFrames, "lockdelay", lockDelayFrames, "frames a piece can stay on the ground before it locks")
+	lockResetName := flag.String("lockreset", "move", "lock delay reset mode: infinity, move or step")
+	gravityName := flag.String("gravity", "naive", "line clear gravity: naive, sticky or cascade")
+	flag.IntVar(&handlings[0].das, "das", handlings[0].das, "delayed auto shift: frames to hold left/right before auto-shifting")
+	flag.IntVar(&handlings[0].arr, "arr", handlings[0].arr, "auto repeat rate: frames between auto-shift steps (0 is instant)")
+	flag.IntVar(&handlings[0].sdf, "sdf", handlings[0].sdf, "soft drop factor: how many times faster than gravity soft drop is")
+	flag.IntVar(&handlings[1].das, "das2", handlings[1].das, "delayed auto shift of player 2 in versus mode")
+	flag.IntVar(&handlings[1].arr, "arr2", handlings[1].arr, "auto repeat rate of player 2 in versus mode")
+	flag.IntVar(&handlings[1].sdf, "sdf2", handlings[1].sdf, "soft drop factor of player 2 in versus mode")
+	width := flag.Int("width", gridSizeX-2, fmt.Sprintf("number of columns of the board (%d-%d)", minBoardWidth, maxBoardWidth))
+	height := flag.Int("height", gridSizeY-2, fmt.Sprintf("number of rows of the board (%d-%d)", minBoardHeight, maxBoardHeight))
+	flag.StringVar(&pieceSetName, "pieces", pieceSetName, "piece set: "+bundledPieceSetNames()+", or the path to a data file")
+	flag.StringVar(&recordPath, "record", "", "record every game to this replay file (each game overwrites the previous one)")
+	replayPath := flag.String("replay", "", "watch the game recorded in this replay file")
+	headless := flag.Bool("headless", false, "let the bot play without opening a window, and print the results")
+	games := flag.Int("games", 10, "number of games the bot plays in headless mode")
+	modeName := flag.String("mode", "marathon", "game mode the bot plays in headless mode: marathon, sprint or ultra")
+	puzzlePath := flag.String("puzzle", "", "puzzle file added to the bundled puzzles ("+bundledPuzzleNames()+")")
+	flag.Parse()
+
+	// A replay is played back with the settings it has been recorded with
+	var watched replay
+	if *replayPath != "" {
+		var err error
+		if watched, err = loadReplay(*replayPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+
+		randomizerName, previewCount, lockDelayFrames = watched.randomizer, watched.previewCount, watched.lockDelayFrames
+		*lockResetName = watched.lockReset
+		copy(handlings[:], watched.handlings)
+		*gravityName = watched.gravity
+		*width, *height, pieceSetName = watched.width, watched.height, watched.pieces
+		*puzzlePath = watched.puzzle
+	}
+
+	if _, err := newRandomizer(randomizerName); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	var err error
+	if skinIndex, err = findSkin(*skinName); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if lockReset, err = parseLockResetMode(*lockResetName); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if lockDelayFrames < 1 {
+		fmt.Fprintln(os.Stderr, "invalid lock delay: it must be 1 frame or more")
+		os.Exit(2)
+	}
+
+	if lineClearGravity, err = parseClearGravity(*gravityName); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	for _, h := range handlings {
+		if h.das < 0 || h.arr < 0 || h.sdf < 1 {
+			fmt.Fprintln(os.Stderr, "invalid handling: das and arr must be 0 or more, and sdf 1 or more")
+			os.Exit(2)
+		}
+	}
+
+	if previewCount < 1 || previewCount > maxPreviewCount {
+		fmt.Fprintf(os.Stderr, "invalid preview count %d (valid: 1-%d)\n", previewCount, maxPreviewCount)
+		os.Exit(2)
+	}
+
+	if *width < minBoardWidth || *width > maxBoardWidth || *height < minBoardHeight || *height > maxBoardHeight {
+		fmt.Fprintf(os.Stderr, "invalid board size %dx%d (valid: %d-%d columns and %d-%d rows)\n",
+			*width, *height, minBoardWidth, maxBoardWidth, minBoardHeight, maxBoardHeight)
+		os.Exit(2)
+	}
+
+	gridSizeX, gridSizeY = *width+2, *height+2
+
+	// Shrink the squares of big boards, so that the grid (and both grids in versus mode) fits the screen
+	if size := (screenHeight - 50) / gridSizeY; size < squareSize {
+		squareSize = size
+	}
+
+	if size := 280 / gridSizeX; size < squareSize {
+		squareSize = size
+	}
+
+	if err = loadPieceSet(pieceSetName); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if size := maxPieceSize(); size > *width || size > *height {
+		fmt.Fprintf(os.Stderr, "the board is too small for the %s pieces: they need %dx%d squares\n", pieceSetName, size, size)
+		os.Exit(2)
+	}
+
+	if err = loadPuzzles(*puzzlePath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if *headless {
+		if selectedMode, err = findGameMode(*modeName); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+
+		if selectedMode == modeZen || selectedMode == modeVersus || selectedMode == modePuzzle {
+			fmt.Fprintln(os.Stderr, "the bot can only play marathon, sprint and ultra in headless mode")
+			os.Exit(2)
+		}
+
+		if *games < 1 {
+			fmt.Fprintln(os.Stderr, "invalid number of games: it must be 1 or more")
+			os.Exit(2)
+		}
+
+		runHeadless(*games)
+		return
+	}
+
+	// Initialize the game the first time, and let the player pick a game mode
+	reset()
+	isSelectingMode = true
+
 	rl.InitWindow(screenWidth, screenHeight, "classic game: tetris")
 	rl.SetTargetFPS(60)
 
+	// NOTE: The replay has to be started once the window is open, as opening it seeds the random number generator
+	if *replayPath != "" {
+		startPlayback(watched)
+	}
+
 	// Main game loop
 	for !rl.WindowShouldClose() { // Detect window close button or ESC key
 		UpdateDrawFrame()
@@ -124,7 +366,17 @@ func main() {
 // It's called when the game starts, and when the player loses (gameover).
 func reset() {
 	score = 0
-	fadingColor = rl.Gray
+	framesCounter = 0
+	lines = 0
+	level = 1
+	combo = -1
+	isBackToBack = false
+	chain = 0
+	isLastMoveRotation = false
+	lastKickIndex = 0
+	isLastRotation180 = false
+	spinLabelCounter = 0
+	isFadingFlashOn = false
 
 	// Keep track of the piece that is falling down
 	piecePosX = 0
@@ -136,16 +388,24 @@ func reset() {
 	isPieceFalling = false
 	isDownCollided = false
 	hasLineToDelete = false
+	isHolding = false
+	canHold = true
 
 	// Counters
+	playerHandling = handlings[0]
 	verticalMoveCounter = 0
-	horizontalMoveCounter = 0
+	dasCounter = 0
+	arrCounter = 0
+	shiftDirection = 0
 	turnMovementCounter = 0
 	fastFallMoveCounter = 0
 	fadeLineCounter = 0
+	lockDelayCounter = 0
+	lockResets = 0
+	lowestRow = 0
 
-	// We use a 12x20 grid, but we only play the game inside a smaller 10x18 grid, so we have to leave 2 squares
-	// empty on each side of the grid.
+	// We use a 12x20 grid by default (see the -width and -height flags), but we only play the game inside
+	// a smaller 10x18 grid, so we have to leave 2 squares empty on each side of the grid.
 	//
 	//  The grid is composed of 5 types of squares:
 	//
@@ -157,6 +417,27 @@ func reset() {
 	//   			 and it is going to be deleted
 	//
 	// Initialize the main gaming grid area with empty squares and surrounding walls
+	clearGrid()
+
+	// Start a new sequence of incoming pieces
+	// NOTE: The randomizer is created from scratch so that every game has its own bag (or history)
+	pieceRandomizer, _ = newRandomizer(randomizerName)
+	nextQueue = nextQueue[:0]
+	spawnCounter = 0
+	botPlannedPiece = 0
+
+	pendingGarbage = 0
+	outgoingGarbage = 0
+	sentGarbage = 0
+}
+
+// clearGrid empties the gaming grid, along with the kind and the lock id of every square, and builds the walls.
+func clearGrid() {
+	grid = newMatrix[gridSquare](gridSizeX, gridSizeY)
+	gridKind = newMatrix[tetromino](gridSizeX, gridSizeY)
+	gridPiece = newMatrix[int](gridSizeX, gridSizeY)
+	lockedPieces = 0
+
 	for i := 0; i < gridSizeX; i++ {
 		for j := 0; j < gridSizeY; j++ {
 			isBottomWall := j == gridSizeY-1
@@ -170,28 +451,35 @@ func reset() {
 			}
 		}
 	}
-
-	// Initialize preview area grid for incoming piece
-	// NOTE: We could use the same grid for the preview area, but we prefer to use a different one
-	//       to avoid possible problems with the main grid
-	for i := 0; i < 4; i++ {
-		for j := 0; j < 4; j++ {
-			incomingPiece[i][j] = EMPTY
-		}
-	}
 }
 
 // UpdateGame Update game logic (one frame)
 func UpdateGame() {
-	// 1. Check if the game is over (if the player has lost)
+	// 0. Before playing, the player has to choose a game mode
+	if isSelectingMode {
+		updateModeSelection()
+		return
+	}
+
+	// 1. Check if the game is over (if the player has lost or reached the goal of the game mode)
 	if !isGameover {
 		// 2. If the game is _not_ over, then check if the game is paused,
 		//    and if so, wait for the user to press P to continue
-		if rl.IsKeyPressed(rl.KeyP) {
+		if isKeyPressed(rl.KeyP) {
 			isPaused = !isPaused
 		}
 		// 3. If the game is _not_ paused, then proceed to the next step.
 		if !isPaused {
+			framesCounter++
+
+			if spinLabelCounter > 0 {
+				spinLabelCounter--
+			}
+
+			if finesseLabelCounter > 0 {
+				finesseLabelCounter--
+			}
+
 			// 4. Check if a line has been completed, and if so, we have to delete it.
 			if !hasLineToDelete {
 				// 5. If there is no line to delete, then check if a piece is active (falling down)
@@ -208,65 +496,91 @@ func UpdateGame() {
 					// 6b. The piece is active (currently falling down), so we check for:
 					//     user input, movement, collisions and game over.
 
+					// 6b.0 Check if the user wants to stash the piece into the hold slot (once per piece).
+					//      NOTE: The pieces of a puzzle must be played in order, so there is no hold slot.
+					if (isKeyPressed(rl.KeyC) || isKeyPressed(rl.KeyLeftShift)) && canHold && currentMode != modePuzzle {
+						holdPiece()
+					}
+
+					// In puzzles, count the inputs spent on the piece to judge its finesse (see puzzle.go)
+					if currentMode == modePuzzle {
+						countFinesseInputs()
+					}
+
+					// Remember where the piece was, to know if the player managed to move or rotate it during this frame
+					prevPosX, prevPosY, prevRotation := piecePosX, piecePosY, pieceRotation
+
 					// 6b.1 Counters update
-					//      they count they number of frames until the piece moves down, left, right or rotates)
+					//      they count they number of frames until the piece moves down faster or rotates)
 					fastFallMoveCounter++
-					verticalMoveCounter++
-					horizontalMoveCounter++
 					turnMovementCounter++
 
-					// 6b.2 Check if the user has pressed the left or right key to move the piece horizontally.
-					if rl.IsKeyPressed(rl.KeyLeft) || rl.IsKeyPressed(rl.KeyRight) {
-						horizontalMoveCounter = framesToWaitBeforeLateralMovement
-					}
-
-					// 6b.3 Check if the user has pressed the up key to turn the piece.
-					if rl.IsKeyPressed(rl.KeyUp) {
+					// 6b.2 Check if the user has pressed any of the rotation keys to turn the piece.
+					if isKeyPressed(rl.KeyUp) || isKeyPressed(rl.KeyX) || isKeyPressed(rl.KeyZ) || isKeyPressed(rl.KeyA) {
 						turnMovementCounter = speedTurn
 					}
 
-					// 6b.4 Check if the user has pressed the down key to move the piece down faster.
-					if rl.IsKeyDown(rl.KeyDown) && (fastFallMoveCounter >= fastFallAwaitCounter) {
-						verticalMoveCounter += framesToWaitBeforeMoveDown // Move the piece down faster
-					}
+					// 6b.3 Check if the user is holding the down key to move the piece down faster (SDF times faster).
+					isSoftDropping := isKeyDown(rl.KeyDown) && (fastFallMoveCounter >= fastFallAwaitCounter)
+					verticalMoveCounter += gravityStep(isSoftDropping)
 
-					// 6b.5 Check if the number of frames (verticalMoveCounter) has reached the limit, and if so, move the piece down.
-					if verticalMoveCounter >= framesToWaitBeforeMoveDown {
-						verticalMoveCounter = 0 // Reset the counter
+					// 6b.4 Check if the number of frames (verticalMoveCounter) has reached the limit, and if so, move the piece down.
+					//      NOTE: With a high enough SDF the piece can move down more than one cell per frame
+					for verticalMoveCounter >= dropInterval() {
+						verticalMoveCounter -= dropInterval()
 
-						// 6b.5.1 Check if the piece has collided with the bottom of the grid or with another piece
+						// 6b.4.1 Check if the piece has collided with the bottom of the grid or with another piece
+						// NOTE: A piece on the ground is locked by the lock delay (6b.8), not here
 						isDownCollided = checkCollisionY()
 						if isDownCollided {
-							stopMovingDown()
-						} else {
-							moveDown()
+							verticalMoveCounter = 0 // Reset the counter
+							break
 						}
-						// 6b.5.2 Check if the player has completed a line, and if so, mark it (FADING) to be deleted in the next frame
-						CheckCompletion(&hasLineToDelete)
-					}
 
-					// 6b.6 Move laterally at player's will
-					if horizontalMoveCounter >= framesToWaitBeforeLateralMovement {
-						// Update the lateral movement and if success, reset the lateral counter
-						if !ResolveLateralMovement() {
-							horizontalMoveCounter = 0
+						moveDown()
+						onPieceStepDown()
+
+						if isSoftDropping {
+							score += softDropPoints
 						}
+
+						// Don't count the step down as a player's move in 6b.8
+						prevPosY = piecePosY
 					}
 
-					// 6b.7 Turn the piece at player's will
+					// 6b.5 Move laterally at player's will, following the DAS/ARR rules (see das.go)
+					updateAutoShift()
+
+					// 6b.6 Turn the piece at player's will
 					if turnMovementCounter >= speedTurn {
 						// Update the turning movement and reset the turning counter
 						if ResolveTurnMovement() {
 							turnMovementCounter = 0
 						}
 					}
+
+					// 6b.7 Hard drop: place the piece at its landing row and lock it right away.
+					if isKeyPressed(rl.KeySpace) {
+						hardDrop()
+					}
+
+					// 6b.8 Lock delay: moving or rotating the piece may give the player more time (see lockdelay.go),
+					//      then lock the piece if it has been on the ground for long enough.
+					//      If the player has completed a line, it is marked (FADING) to be deleted in the next frame
+					if isPieceFalling {
+						if piecePosX != prevPosX || piecePosY != prevPosY || pieceRotation != prevRotation {
+							onPieceMoved()
+						}
+
+						updateLockDelay()
+					}
 				}
 
-				// 6b.8 If the piece has reached the top of the grid, then the game is over.
+				// 6b.9 If the piece has reached the top of the grid, then the game is over (see topOut).
 				for j := 0; j < 2; j++ {
 					for i := 1; i < gridSizeX-1; i++ {
-						if grid[i][j] == FULL {
-							isGameover = true
+						if grid[i][j] == FULL && !isGameover {
+							topOut()
 						}
 					}
 				}
@@ -274,25 +588,27 @@ func UpdateGame() {
 				// Animation when deleting score
 				fadeLineCounter++
 
-				if fadeLineCounter%8 < 4 {
-					fadingColor = rl.Maroon
-				} else {
-					fadingColor = rl.Gray
-				}
+				isFadingFlashOn = fadeLineCounter%8 < 4
 
 				if fadeLineCounter >= timeToFade {
-					deletedLines := DeleteCompleteLines()
+					// NOTE: The lines have already been scored when the piece was locked (see lockPiece).
+					//       If the squares falling complete new lines, they fade in turn (see cleargravity.go)
 					fadeLineCounter = 0
 					hasLineToDelete = false
-
-					score += deletedLines
+					deleteLines()
 				}
 			}
+
+			// 7. Check if the goal of the game mode has been reached (see modes.go).
+			if !hasLineToDelete && !isGameover {
+				checkModeEnd()
+			}
 		}
 	} else {
-		if rl.IsKeyPressed(rl.KeyEnter) {
+		if isKeyPressed(rl.KeyEnter) {
 			reset()
 			isGameover = false
+			isSelectingMode = true
 		}
 	}
 }
@@ -312,111 +628,266 @@ func moveDown() {
 	}
 
 	piecePosY++ // Update piece position information, one cell down
+
+	isLastMoveRotation = false
 }
 
 // stopMovingDown converts the MOVING squares to FULL and resets the related boolean flags
 func stopMovingDown() {
+	lockedPieces++
+
 	for j := gridSizeY - 2; j >= 0; j-- { // We start from the bottom of the grid
 		for i := 1; i < gridSizeX-1; i++ { // We start from the left side of the grid
 			if grid[i][j] == MOVING { // If the square is part of the moving piece
-				grid[i][j] = FULL      // Convert it to FULL
-				isDownCollided = false // Reset the ground flag
-				isPieceFalling = false // Reset the falling flag
+				grid[i][j] = FULL              // Convert it to FULL
+				gridKind[i][j] = pieceKind     // Remember which tetromino it comes from
+				gridPiece[i][j] = lockedPieces // ... and which piece
+				isDownCollided = false         // Reset the ground flag
+				isPieceFalling = false         // Reset the falling flag
 			}
 		}
 	}
+
+	canHold = true // The next piece can be held again
+}
+
+// lockPiece locks the moving piece into the grid, marks the completed lines (FADING) to be deleted
+// and scores them.
+func lockPiece() {
+	spin := detectTSpin()
+	chain = 0
+
+	stopMovingDown()
+	CheckCompletion(&hasLineToDelete)
+
+	if currentMode == modePuzzle {
+		judgeFinesse()
+	}
+
+	clearedLines := countFadingLines()
+	scoreLock(clearedLines, spin)
+	countClearedLines(clearedLines, spin)
+}
+
+// countClearedLines counts the lines that have just been completed (FADING), by locking a piece or by a chain
+// (see cleargravity.go): it updates the lines and the level, the garbage in versus mode and the goal of a puzzle.
+func countClearedLines(clearedLines int, spin tSpin) {
+	lines += clearedLines
+	level = 1 + lines/linesPerLevel
+
+	if currentMode == modeVersus {
+		updateGarbage(clearedLines)
+	}
+
+	if currentMode == modePuzzle {
+		updatePuzzle(clearedLines, spin)
+	}
 }
 
 // DrawGame Draw game (one frame)
 func DrawGame() {
 	rl.BeginDrawing()
 
-	rl.ClearBackground(rl.RayWhite)
+	rl.ClearBackground(currentSkin().background)
 
-	if !isGameover {
+	if isSelectingMode {
+		drawModeSelection()
+	} else if currentMode == modeVersus {
+		drawVersus()
+	} else if !isGameover {
 		// Draw gameplay area
 		offset := rl.Vector2{
 			// X Offset the grid to the center of the screen
-			X: screenWidth/2 - (gridSizeX * squareSize),
+			X: float32(screenWidth/2 - (gridSizeX * squareSize)),
 			// Y Offset the grid to the bottom of the screen
-			Y: screenHeight/2 - ((gridSizeY - 1) * squareSize / 2) + squareSize*2,
+			Y: float32(screenHeight/2 - ((gridSizeY - 1) * squareSize / 2) + squareSize*2),
 		}
 
 		offset.X -= 50 // offset to the left
 		offset.Y -= 50 // NOTE: Hardcoded position! Places the bottom of the grid a bit higher
 
-		controller := offset.X
+		drawGrid(offset)
 
-		for j := 0; j < gridSizeY; j++ {
-			for i := 0; i < gridSizeX; i++ {
-				// Draw each square of the grid
-				switch grid[i][j] {
-				case EMPTY:
-					DrawLine(offset.X, offset.Y, offset.X+squareSize, offset.Y, rl.LightGray)
-					DrawLine(offset.X, offset.Y, offset.X, offset.Y+squareSize, rl.LightGray)
-					DrawLine(offset.X+squareSize, offset.Y, offset.X+squareSize, offset.Y+squareSize, rl.DarkGray)
-					DrawLine(offset.X, offset.Y+squareSize, offset.X+squareSize, offset.Y+squareSize, rl.DarkGray)
-					offset.X += squareSize
-				case FULL:
-					DrawRectangle(offset.X, offset.Y, squareSize, squareSize, rl.Gray)
-					offset.X += squareSize
-				case MOVING:
-					DrawRectangle(offset.X, offset.Y, squareSize, squareSize, rl.DarkGray)
-					offset.X += squareSize
-				case BLOCK:
-					DrawRectangle(offset.X, offset.Y, squareSize, squareSize, rl.LightGray)
-					offset.X += squareSize
-				case FADING:
-					DrawRectangle(offset.X, offset.Y, squareSize, squareSize, fadingColor)
-					offset.X += squareSize
-				}
+		// Draw incoming pieces (hardcoded): the first one in full size, and the rest of the queue smaller below it
+		// NOTE: The queue of a puzzle ends with noTetromino once its pieces run out
+		for k, kind := range nextQueue {
+			if kind == noTetromino {
+				break
 			}
 
-			offset.X = controller
-			offset.Y += squareSize
+			if k == 0 {
+				drawPreviewBox(tetrominoShape(kind, rotation0), 500, 45, 20, currentSkin().lockedColor(kind))
+			} else {
+				drawPreviewBox(tetrominoShape(kind, rotation0), 500, 135+float32(k-1)*45, 10, currentSkin().lockedColor(kind))
+			}
 		}
 
-		// Draw incoming piece (hardcoded)
-		offset.X = 500
-		offset.Y = 45
-
-		controller = offset.X
-
-		for j := 0; j < 4; j++ {
-			for i := 0; i < 4; i++ {
-				if incomingPiece[i][j] == EMPTY {
-					DrawLine(offset.X, offset.Y, offset.X+squareSize, offset.Y, rl.LightGray)                       // top line
-					DrawLine(offset.X, offset.Y, offset.X, offset.Y+squareSize, rl.LightGray)                       // left line
-					DrawLine(offset.X+squareSize, offset.Y, offset.X+squareSize, offset.Y+squareSize, rl.LightGray) // right line
-					DrawLine(offset.X, offset.Y+squareSize, offset.X+squareSize, offset.Y+squareSize, rl.LightGray) // bottom line
-					offset.X += squareSize
-				} else if incomingPiece[i][j] == MOVING {
-					DrawRectangle(offset.X, offset.Y, squareSize, squareSize, rl.Gray)
-					offset.X += squareSize
-				}
-			}
+		DrawText("NEXT:", 500, 25, 10, rl.Gray)
+
+		// Draw the hold slot (hardcoded) at the left of the grid, greyed out while it can't be used
+		holdShape := newMatrix[gridSquare](4, 4)
+		holdColor := currentSkin().gridLines
+		if isHolding {
+			holdShape = tetrominoShape(holdKind, rotation0)
+			holdColor = currentSkin().lockedColor(holdKind)
+		}
 
-			offset.X = controller
-			offset.Y += squareSize
+		if !canHold {
+			holdColor = currentSkin().gridLines
 		}
 
-		DrawText("INCOMING:", offset.X, offset.Y-100, 10, rl.Gray)
-		DrawText(fmt.Sprintf("LINES: %04d", score), 500, 250, 20, rl.Gray)
+		drawPreviewBox(holdShape, 20, 45, 20, holdColor)
+		DrawText("HOLD:", 20, 25, 10, rl.Gray)
+
+		drawSpinLabel(20, 150)
+		drawFinesseLabel(20, 175)
+		drawStatistics(620, 45)
+		drawModeHUD(620, 175)
+
+		DrawText(fmt.Sprintf("SKIN: %s [TAB]", currentSkin().name), 620, 420, 10, rl.Gray)
+
+		if isBotPlaying {
+			DrawText("BOT PLAYING [B]", 620, 405, 10, rl.Maroon)
+		}
 
 		if isPaused {
 			rl.DrawText("GAME PAUSED", screenWidth/2-rl.MeasureText("GAME PAUSED", 40)/2, screenHeight/2-40, 40, rl.Gray)
 		}
 	} else {
-		const replayMsg = "PRESS [ENTER] TO PLAY AGAIN"
-		DrawText(replayMsg, rl.GetScreenWidth()/2-MeasureText(replayMsg, 20)/2, rl.GetScreenHeight()/2-50, 20, rl.Gray)
+		drawResults()
 	}
 
+	drawPlaybackHUD()
+
 	rl.EndDrawing()
 }
 
+// drawGrid draws the grid (and the ghost piece) with its top-left corner at the given offset.
+func drawGrid(offset rl.Vector2) {
+	controller := offset.X
+	squareSize := float32(squareSize)
+
+	// The ghost piece shows where the moving piece would land, that is, the MOVING squares shifted down
+	ghostDistance := 0
+	if isPieceFalling && !hasLineToDelete {
+		ghostDistance = landingDistance()
+	}
+
+	for j := 0; j < gridSizeY; j++ {
+		for i := 0; i < gridSizeX; i++ {
+			// Draw each square of the grid
+			switch grid[i][j] {
+			case EMPTY:
+				DrawLine(offset.X, offset.Y, offset.X+squareSize, offset.Y, currentSkin().gridLines)
+				DrawLine(offset.X, offset.Y, offset.X, offset.Y+squareSize, currentSkin().gridLines)
+				DrawLine(offset.X+squareSize, offset.Y, offset.X+squareSize, offset.Y+squareSize, currentSkin().gridShadow)
+				DrawLine(offset.X, offset.Y+squareSize, offset.X+squareSize, offset.Y+squareSize, currentSkin().gridShadow)
+
+				if ghostDistance > 0 && j-ghostDistance >= 0 && grid[i][j-ghostDistance] == MOVING {
+					DrawRectangleLines(offset.X+1, offset.Y+1, squareSize-2, squareSize-2, currentSkin().ghost)
+				}
+
+				offset.X += squareSize
+			case FULL:
+				DrawRectangle(offset.X, offset.Y, squareSize, squareSize, currentSkin().lockedColor(gridKind[i][j]))
+				offset.X += squareSize
+			case MOVING:
+				DrawRectangle(offset.X, offset.Y, squareSize, squareSize, currentSkin().movingColor(pieceKind))
+				offset.X += squareSize
+			case BLOCK:
+				DrawRectangle(offset.X, offset.Y, squareSize, squareSize, currentSkin().wall)
+				offset.X += squareSize
+			case FADING:
+				if isFadingFlashOn {
+					DrawRectangle(offset.X, offset.Y, squareSize, squareSize, currentSkin().flash)
+				} else {
+					DrawRectangle(offset.X, offset.Y, squareSize, squareSize, currentSkin().lockedColor(gridKind[i][j]))
+				}
+				offset.X += squareSize
+			}
+		}
+
+		offset.X = controller
+		offset.Y += squareSize
+	}
+}
+
+// drawPreviewBox draws a piece matrix (e.g. an incoming piece) in a 4x4 box with its top-left corner at (posX, posY).
+// The EMPTY squares are outlined so the player can tell the size of the box.
+// NOTE: Pieces bigger than 4x4 (see pieces.go) are drawn with smaller squares, to fit in the same box.
+func drawPreviewBox(shape [][]gridSquare, posX, posY, size float32, col color.RGBA) {
+	offset := rl.Vector2{X: posX, Y: posY}
+
+	boxSize := 4
+	if len(shape) > boxSize {
+		size = size * float32(boxSize) / float32(len(shape))
+		boxSize = len(shape)
+	}
+
+	for j := 0; j < boxSize; j++ {
+		for i := 0; i < boxSize; i++ {
+			square := EMPTY
+			if i < len(shape) && j < len(shape) {
+				square = shape[i][j]
+			}
+
+			if square == EMPTY {
+				DrawLine(offset.X, offset.Y, offset.X+size, offset.Y, currentSkin().gridLines)           // top line
+				DrawLine(offset.X, offset.Y, offset.X, offset.Y+size, currentSkin().gridLines)           // left line
+				DrawLine(offset.X+size, offset.Y, offset.X+size, offset.Y+size, currentSkin().gridLines) // right line
+				DrawLine(offset.X, offset.Y+size, offset.X+size, offset.Y+size, currentSkin().gridLines) // bottom line
+			} else if square == MOVING {
+				DrawRectangle(offset.X, offset.Y, size, size, col)
+			}
+
+			offset.X += size
+		}
+
+		offset.X = posX
+		offset.Y += size
+	}
+}
+
 // UpdateDrawFrame Update and Draw (one frame)
 func UpdateDrawFrame() {
-	UpdateGame()
+	// These keys are not part of the game, so they are read from the keyboard even while the bot is playing
+	if rl.IsKeyPressed(rl.KeyTab) {
+		nextSkin()
+	}
+
+	if rl.IsKeyPressed(rl.KeyB) {
+		isBotPlaying = !isBotPlaying
+	}
+
+	// While watching a replay, the keyboard controls the playback instead of the game (see replay.go)
+	frames := 1
+	if isReplaying {
+		frames = updatePlaybackControls()
+	}
+
+	// NOTE: Once a fast-forwarded replay ends, the rest of the frames of the batch are not played
+	for f := 0; f < frames && (f == 0 || isReplaying); f++ {
+		// In versus mode both boards are updated side by side, each one with its own keys (see versus.go)
+		if !isSelectingMode && currentMode == modeVersus {
+			updateVersus()
+		} else {
+			switch {
+			case isReplaying:
+				input = replayInput(0)
+			case isBotPlaying && !isSelectingMode && !isGameover:
+				input = botInput()
+			default:
+				input = pollKeyboard()
+			}
+
+			recordInput(0, input)
+
+			UpdateGame()
+		}
+
+		updateReplays()
+	}
+
 	DrawGame()
 }
 
@@ -425,94 +896,87 @@ func UpdateDrawFrame() {
 //--------------------------------------------------------------------------------------
 
 func CreatePiece() bool {
-	piecePosX = (gridSizeX - 4) / 2 // Centerpiece in X axis
-	piecePosY = 0                   // Start piece at top of the grid
-
-	// If the game is starting, and you are going to create the first piece, we create an extra one
+	// If the game is starting, and you are going to create the first piece, we fill the queue of incoming pieces
 	if isFirst {
-		getRandomPiece()
+		for len(nextQueue) < previewCount {
+			getRandomPiece()
+		}
 
 		isFirst = false
 	}
 
-	// We assign the incoming piece to the actual piece
-	for i := 0; i < 4; i++ {
-		for j := 0; j < 4; j++ {
-			piece[i][j] = incomingPiece[i][j]
-		}
+	// The queue of a puzzle ends with noTetromino once its pieces run out: there is nothing left to spawn
+	if nextQueue[0] == noTetromino {
+		checkModeEnd()
+		return false
 	}
 
-	// We assign a random piece to the incoming one
+	// We assign the first incoming piece to the actual piece
+	spawnPiece(nextQueue[0])
+	nextQueue = append(nextQueue[:0], nextQueue[1:]...)
+
+	// We add a random piece to the end of the queue
 	getRandomPiece()
 
+	return true
+}
+
+// spawnPiece makes the given tetromino the active piece, in its spawn state at the top of the grid.
+func spawnPiece(kind tetromino) {
+	pieceKind = kind
+	pieceRotation = rotation0
+	piece = tetrominoShape(pieceKind, pieceRotation)
+
+	piecePosX = tetrominoSpawnX(pieceKind) // Centerpiece in X axis
+	piecePosY = 0                          // Start piece at top of the grid
+	spawnCounter++
+
+	finesseInputs = 0
+	hasSoftDropped = false
+
 	// Assign the piece to the grid
-	for i := piecePosX; i < piecePosX+4; i++ {
-		for j := 0; j < 4; j++ {
-			if piece[i-piecePosX][j] == MOVING {
-				grid[i][j] = MOVING
-			}
-		}
-	}
+	placePiece()
 
-	return true
+	resetLockDelay()
 }
 
-// getRandomPiece Get a random piece
-func getRandomPiece() {
-	random := rl.GetRandomValue(0, 6)
+// holdPiece stashes the active piece into the hold slot.
+// If the slot was empty the next incoming piece becomes active, otherwise the held piece is swapped in,
+// starting again from the top of the grid.
+func holdPiece() {
+	clearMovingSquares()
 
-	for i := 0; i < 4; i++ {
-		for j := 0; j < 4; j++ {
-			incomingPiece[i][j] = EMPTY
-		}
+	if isHolding {
+		holdKind, pieceKind = pieceKind, holdKind
+		spawnPiece(pieceKind)
+	} else {
+		holdKind = pieceKind
+		isHolding = true
+		CreatePiece()
 	}
 
-	switch random {
-	case 0: // O Square
-		incomingPiece[1][1] = MOVING
-		incomingPiece[2][1] = MOVING
-		incomingPiece[1][2] = MOVING
-		incomingPiece[2][2] = MOVING
-	case 1: // L
-		incomingPiece[1][0] = MOVING
-		incomingPiece[1][1] = MOVING
-		incomingPiece[1][2] = MOVING
-		incomingPiece[2][2] = MOVING
-	case 2: // L inverse
-		incomingPiece[1][2] = MOVING
-		incomingPiece[2][0] = MOVING
-		incomingPiece[2][1] = MOVING
-		incomingPiece[2][2] = MOVING
-	case 3: // I Line
-		incomingPiece[0][1] = MOVING
-		incomingPiece[1][1] = MOVING
-		incomingPiece[2][1] = MOVING
-		incomingPiece[3][1] = MOVING
-	case 4: // T Piece
-		incomingPiece[1][0] = MOVING
-		incomingPiece[1][1] = MOVING
-		incomingPiece[1][2] = MOVING
-		incomingPiece[2][1] = MOVING
-	case 5: // Z
-		incomingPiece[1][1] = MOVING
-		incomingPiece[2][1] = MOVING
-		incomingPiece[2][2] = MOVING
-		incomingPiece[3][2] = MOVING
-	case 6: // S or Z inverse
-		incomingPiece[1][2] = MOVING
-		incomingPiece[2][2] = MOVING
-		incomingPiece[2][1] = MOVING
-		incomingPiece[3][1] = MOVING
-	}
+	canHold = false // Only one swap until the piece locks
+	verticalMoveCounter = 0
+}
+
+// getRandomPiece Get a random piece from the randomizer and append it to the queue of incoming pieces
+func getRandomPiece() {
+	nextQueue = append(nextQueue, pieceRandomizer.next())
 }
 
 // checkCollisionY Check if the current moving piece is colliding with the ground or another piece
 func checkCollisionY() bool {
+	return checkCollisionYAt(0)
+}
+
+// checkCollisionYAt Check if the current moving piece would collide with the ground or another piece
+// once moved down by the given number of rows
+func checkCollisionYAt(rows int) bool {
 	for j := gridSizeY - 2; j >= 0; j-- { // We check from the bottom playable line to the top
 		for i := 1; i < gridSizeX-1; i++ { // We check from left to right (playable area)
 			// Check if any square of the piece is colliding with the ground wall (that is NOT playable area) (BLOCK)
 			// or with another piece that is already in the grid (FULL)
-			if grid[i][j] == MOVING && (grid[i][j+1] == FULL || grid[i][j+1] == BLOCK) {
+			if grid[i][j] == MOVING && (grid[i][j+rows+1] == FULL || grid[i][j+rows+1] == BLOCK) {
 				return true
 			}
 		}
@@ -521,6 +985,29 @@ func checkCollisionY() bool {
 	return false
 }
 
+// landingDistance returns how many rows the current moving piece can fall before it collides.
+// NOTE: The bottom wall is BLOCK, so the piece always lands before leaving the grid.
+func landingDistance() int {
+	rows := 0
+	for rows < gridSizeY && !checkCollisionYAt(rows) {
+		rows++
+	}
+
+	return rows
+}
+
+// hardDrop moves the piece straight down to its landing row and locks it there
+func hardDrop() {
+	rows := landingDistance()
+	for k := 0; k < rows; k++ {
+		moveDown()
+	}
+
+	score += hardDropPoints * rows
+
+	lockPiece()
+}
+
 func CheckCompletion(lineToDelete *bool) {
 	var calculator int
 
@@ -547,6 +1034,19 @@ func CheckCompletion(lineToDelete *bool) {
 	}
 }
 
+// countFadingLines returns the number of completed lines waiting to be deleted
+func countFadingLines() int {
+	var fadingLines int
+
+	for j := gridSizeY - 2; j >= 0; j-- {
+		if grid[1][j] == FADING {
+			fadingLines++
+		}
+	}
+
+	return fadingLines
+}
+
 func DeleteCompleteLines() int {
 	var deletedLines int
 
@@ -568,6 +1068,10 @@ func DeleteCompleteLines() int {
 						grid[i2][j2+1] = FADING
 						grid[i2][j2] = EMPTY
 					}
+
+					// The squares keep the colour of the tetromino they come from
+					gridKind[i2][j2+1] = gridKind[i2][j2]
+					gridPiece[i2][j2+1] = gridPiece[i2][j2]
 				}
 			}
 
@@ -578,11 +1082,13 @@ func DeleteCompleteLines() int {
 	return deletedLines
 }
 
-func ResolveLateralMovement() bool {
+// ResolveLateralMovement moves the piece one cell to the left (direction -1) or to the right (direction 1).
+// It returns true if the piece collided with the wall or another piece, and therefore didn't move.
+func ResolveLateralMovement(direction int) bool {
 	collision := false
 
 	// Piece movement
-	if rl.IsKeyDown(rl.KeyLeft) { // Move left
+	if direction < 0 { // Move left
 		// Check if is possible to move to the left
 		for j := gridSizeY - 2; j >= 0; j-- {
 			for i := 1; i < gridSizeX-1; i++ {
@@ -608,8 +1114,9 @@ func ResolveLateralMovement() bool {
 			}
 
 			piecePosX--
+			isLastMoveRotation = false
 		}
-	} else if rl.IsKeyDown(rl.KeyRight) { // Move Right
+	} else if direction > 0 { // Move Right
 		// Check if is possible to move to right
 		for j := gridSizeY - 2; j >= 0; j-- {
 			for i := 1; i < gridSizeX-1; i++ {
@@ -635,163 +1142,34 @@ func ResolveLateralMovement() bool {
 			}
 
 			piecePosX++
+			isLastMoveRotation = false
 		}
 	}
 
 	return collision
 }
 
+// ResolveTurnMovement turns the piece at player's will, using the Super Rotation System (see srs.go):
+// [UP] or [X] turns clockwise, [Z] turns counter-clockwise and [A] turns 180°.
+// It returns true if a rotation was requested, whether there was room for the piece or not.
 func ResolveTurnMovement() bool {
-	// Input for turning the piece
-	if rl.IsKeyDown(rl.KeyUp) {
-		var (
-			aux     gridSquare
-			checker bool
-		)
-
-		// Check all turning possibilities
-		if (grid[piecePosX+3][piecePosY] == MOVING) &&
-			(grid[piecePosX][piecePosY] != EMPTY) &&
-			(grid[piecePosX][piecePosY] != MOVING) {
-			checker = true
-		}
-
-		if (grid[piecePosX+3][piecePosY+3] == MOVING) &&
-			(grid[piecePosX+3][piecePosY] != EMPTY) &&
-			(grid[piecePosX+3][piecePosY] != MOVING) {
-			checker = true
-		}
-
-		if (grid[piecePosX][piecePosY+3] == MOVING) &&
-			(grid[piecePosX+3][piecePosY+3] != EMPTY) &&
-			(grid[piecePosX+3][piecePosY+3] != MOVING) {
-			checker = true
-		}
-
-		if (grid[piecePosX][piecePosY] == MOVING) &&
-			(grid[piecePosX][piecePosY+3] != EMPTY) &&
-			(grid[piecePosX][piecePosY+3] != MOVING) {
-			checker = true
-		}
-
-		if (grid[piecePosX+1][piecePosY] == MOVING) &&
-			(grid[piecePosX][piecePosY+2] != EMPTY) &&
-			(grid[piecePosX][piecePosY+2] != MOVING) {
-			checker = true
-		}
-
-		if (grid[piecePosX+3][piecePosY+1] == MOVING) &&
-			(grid[piecePosX+1][piecePosY] != EMPTY) &&
-			(grid[piecePosX+1][piecePosY] != MOVING) {
-			checker = true
-		}
-
-		if (grid[piecePosX+2][piecePosY+3] == MOVING) &&
-			(grid[piecePosX+3][piecePosY+1] != EMPTY) &&
-			(grid[piecePosX+3][piecePosY+1] != MOVING) {
-			checker = true
-		}
-
-		if (grid[piecePosX][piecePosY+2] == MOVING) &&
-			(grid[piecePosX+2][piecePosY+3] != EMPTY) &&
-			(grid[piecePosX+2][piecePosY+3] != MOVING) {
-			checker = true
-		}
-
-		if (grid[piecePosX+2][piecePosY] == MOVING) &&
-			(grid[piecePosX][piecePosY+1] != EMPTY) &&
-			(grid[piecePosX][piecePosY+1] != MOVING) {
-			checker = true
-		}
-
-		if (grid[piecePosX+3][piecePosY+2] == MOVING) &&
-			(grid[piecePosX+2][piecePosY] != EMPTY) &&
-			(grid[piecePosX+2][piecePosY] != MOVING) {
-			checker = true
-		}
-
-		if (grid[piecePosX+1][piecePosY+3] == MOVING) &&
-			(grid[piecePosX+3][piecePosY+2] != EMPTY) &&
-			(grid[piecePosX+3][piecePosY+2] != MOVING) {
-			checker = true
-		}
-
-		if (grid[piecePosX][piecePosY+1] == MOVING) &&
-			(grid[piecePosX+1][piecePosY+3] != EMPTY) &&
-			(grid[piecePosX+1][piecePosY+3] != MOVING) {
-			checker = true
-		}
+	var direction rotationDirection
 
-		if (grid[piecePosX+1][piecePosY+1] == MOVING) &&
-			(grid[piecePosX+1][piecePosY+2] != EMPTY) &&
-			(grid[piecePosX+1][piecePosY+2] != MOVING) {
-			checker = true
-		}
-
-		if (grid[piecePosX+2][piecePosY+1] == MOVING) &&
-			(grid[piecePosX+1][piecePosY+1] != EMPTY) &&
-			(grid[piecePosX+1][piecePosY+1] != MOVING) {
-			checker = true
-		}
-
-		if (grid[piecePosX+2][piecePosY+2] == MOVING) &&
-			(grid[piecePosX+2][piecePosY+1] != EMPTY) &&
-			(grid[piecePosX+2][piecePosY+1] != MOVING) {
-			checker = true
-		}
-
-		if (grid[piecePosX+1][piecePosY+2] == MOVING) &&
-			(grid[piecePosX+2][piecePosY+2] != EMPTY) &&
-			(grid[piecePosX+2][piecePosY+2] != MOVING) {
-			checker = true
-		}
-
-		if !checker {
-			aux = piece[0][0]
-			piece[0][0] = piece[3][0]
-			piece[3][0] = piece[3][3]
-			piece[3][3] = piece[0][3]
-			piece[0][3] = aux
-
-			aux = piece[1][0]
-			piece[1][0] = piece[3][1]
-			piece[3][1] = piece[2][3]
-			piece[2][3] = piece[0][2]
-			piece[0][2] = aux
-
-			aux = piece[2][0]
-			piece[2][0] = piece[3][2]
-			piece[3][2] = piece[1][3]
-			piece[1][3] = piece[0][1]
-			piece[0][1] = aux
-
-			aux = piece[1][1]
-			piece[1][1] = piece[2][1]
-			piece[2][1] = piece[2][2]
-			piece[2][2] = piece[1][2]
-			piece[1][2] = aux
-		}
-
-		for j := gridSizeY - 2; j >= 0; j-- {
-			for i := 1; i < gridSizeX-1; i++ {
-				if grid[i][j] == MOVING {
-					grid[i][j] = EMPTY
-				}
-			}
-		}
-
-		for i := piecePosX; i < piecePosX+4; i++ {
-			for j := piecePosY; j < piecePosY+4; j++ {
-				if piece[i-piecePosX][j-piecePosY] == MOVING {
-					grid[i][j] = MOVING
-				}
-			}
-		}
-
-		return true
+	// Input for turning the piece
+	switch {
+	case isKeyDown(rl.KeyUp) || isKeyDown(rl.KeyX):
+		direction = rotateClockwise
+	case isKeyDown(rl.KeyZ):
+		direction = rotateCounterClockwise
+	case isKeyDown(rl.KeyA):
+		direction = rotate180
+	default:
+		return false
 	}
 
-	return false
+	rotatePiece(direction)
+
+	return true
 }
 
 // ------------------------------------------------------------------------------------.
@@ -813,6 +1191,11 @@ func DrawRectangle[T Number](posX, posY, width, height T, col color.RGBA) {
 	rl.DrawRectangle(int32(posX), int32(posY), int32(width), int32(height), col)
 }
 
+// DrawRectangleLines It's the same as rl.DrawRectangleLines but works with any Number type, to avoid type casting pollution.
+func DrawRectangleLines[T Number](posX, posY, width, height T, col color.RGBA) {
+	rl.DrawRectangleLines(int32(posX), int32(posY), int32(width), int32(height), col)
+}
+
 // DrawText It's the same as rl.DrawText but works with any Number type, to avoid type casting pollution.
 func DrawText[T Number](text string, posX, posY, fontSize T, col color.RGBA) {
 	rl.DrawText(text, int32(posX), int32(posY), int32(fontSize), col)
@@ -822,3 +1205,13 @@ func DrawText[T Number](text string, posX, posY, fontSize T, col color.RGBA) {
 func MeasureText[T Number](text string, fontSize T) T {
 	return T(rl.MeasureText(text, int32(fontSize)))
 }
+
+// newMatrix returns a width x height matrix, indexed by [x][y] like the grid.
+func newMatrix[T any](width, height int) [][]T {
+	matrix := make([][]T, width)
+	for x := range matrix {
+		matrix[x] = make([]T, height)
+	}
+
+	return matrix
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ------------------------------------------------------------------------------------
+// Game modes
+// ------------------------------------------------------------------------------------
+
+const (
+	marathonLines = 150         // Lines to clear to complete a Marathon game
+	sprintLines   = 40          // Lines to clear to complete a Sprint game
+	ultraFrames   = 2 * 60 * 60 // Duration of an Ultra game (2 minutes at 60 frames per second)
+)
+
+type gameMode int
+
+const (
+	modeMarathon gameMode = iota // Clear marathonLines lines, the speed rises with the level
+	modeSprint                   // Clear sprintLines lines as fast as possible
+	modeUltra                    // Score as many points as possible in ultraFrames frames
+	modeZen                      // Endless relaxed play, topping out just clears the board
+	modeVersus                   // Two players side by side, sending garbage to each other (see versus.go)
+	modePuzzle                   // Reach the objective of a puzzle with its pieces (see puzzle.go)
+	modeCount
+)
+
+// gameModes holds the name and the description shown in the mode selector, for every game mode.
+var gameModes = [modeCount]struct {
+	name        string
+	description string
+}{
+	modeMarathon: {"MARATHON", fmt.Sprintf("Clear %d lines. The pieces fall faster every level.", marathonLines)},
+	modeSprint:   {"SPRINT", fmt.Sprintf("Clear %d lines as fast as you can.", sprintLines)},
+	modeUltra:    {"ULTRA", fmt.Sprintf("Score as much as you can in %s.", formatTime(ultraFrames))},
+	modeZen:      {"ZEN", "No goal, no top-out. Press [BACKSPACE] to end the session."},
+	modeVersus:   {"VERSUS", "Two players. Clear lines to send garbage to your opponent, the last one standing wins."},
+	modePuzzle:   {"PUZZLE", "Solve a puzzle with the given pieces, without wasting inputs (finesse trainer)."},
+}
+
+// findGameMode returns the game mode with the given name (case insensitive).
+func findGameMode(name string) (gameMode, error) {
+	names := make([]string, modeCount)
+	for mode := gameMode(0); mode < modeCount; mode++ {
+		if strings.EqualFold(gameModes[mode].name, name) {
+			return mode, nil
+		}
+
+		names[mode] = strings.ToLower(gameModes[mode].name)
+	}
+
+	return 0, fmt.Errorf("unknown game mode %q (valid: %s)", name, strings.Join(names, ", "))
+}
+
+// gameResult tells how a game has ended.
+type gameResult int
+
+const (
+	resultTopOut    gameResult = iota // The stack has reached the top of the grid
+	resultCompleted                   // The goal of the mode has been reached (lines cleared or time up)
+	resultEnded                       // The player has ended a Zen session
+	resultFailed                      // The pieces of the puzzle have run out before reaching its objective
+)
+
+// formatTime formats a number of frames as minutes, seconds and hundredths (e.g. "1:05.33").
+func formatTime(frames int) string {
+	hundredths := frames * 100 / 60
+
+	return fmt.Sprintf("%d:%02d.%02d", hundredths/6000, hundredths/100%60, hundredths%100)
+}
+
+// updateModeSelection lets the player pick the game mode with the arrow keys and start playing with [ENTER].
+// [LEFT] and [RIGHT] choose the puzzle, in puzzle mode.
+func updateModeSelection() {
+	if isKeyPressed(rl.KeyUp) {
+		selectedMode = (selectedMode + modeCount - 1) % modeCount
+	}
+
+	if isKeyPressed(rl.KeyDown) {
+		selectedMode = (selectedMode + 1) % modeCount
+	}
+
+	if selectedMode == modePuzzle && len(puzzles) > 0 {
+		if isKeyPressed(rl.KeyLeft) {
+			puzzleIndex = (puzzleIndex + len(puzzles) - 1) % len(puzzles)
+		}
+
+		if isKeyPressed(rl.KeyRight) {
+			puzzleIndex = (puzzleIndex + 1) % len(puzzles)
+		}
+	}
+
+	if isKeyPressed(rl.KeyEnter) && (selectedMode != modePuzzle || len(puzzles) > 0) {
+		currentMode = selectedMode
+		isSelectingMode = false
+
+		startRecording()
+		reset()
+
+		switch currentMode {
+		case modeVersus:
+			startVersus()
+		case modePuzzle:
+			startPuzzle(puzzles[puzzleIndex])
+		}
+	}
+}
+
+// checkModeEnd ends the game once the goal of the current mode has been reached.
+func checkModeEnd() {
+	switch currentMode {
+	case modeMarathon:
+		if lines >= marathonLines {
+			endGame(resultCompleted)
+		}
+	case modeSprint:
+		if lines >= sprintLines {
+			endGame(resultCompleted)
+		}
+	case modeUltra:
+		if framesCounter >= ultraFrames {
+			endGame(resultCompleted)
+		}
+	case modeZen:
+		if isKeyPressed(rl.KeyBackspace) {
+			endGame(resultEnded)
+		}
+	case modePuzzle:
+		if isPuzzleSolved() {
+			endGame(resultCompleted)
+		} else if isPuzzleOver() {
+			endGame(resultFailed)
+		}
+	}
+}
+
+// topOut is called when the stack has reached the top of the grid.
+// It ends the game, except in Zen mode where the board is just cleared.
+func topOut() {
+	if currentMode != modeZen {
+		endGame(resultTopOut)
+		return
+	}
+
+	clearGrid()
+
+	isPieceFalling = false
+	hasLineToDelete = false
+}
+
+// endGame finishes the current game and shows the result screen.
+func endGame(result gameResult) {
+	isGameover = true
+	gameOverResult = result
+}
+
+// drawModeHUD draws the goal and the clock of the current mode.
+func drawModeHUD(posX, posY int32) {
+	switch currentMode {
+	case modeMarathon:
+		DrawText(fmt.Sprintf("GOAL: %d/%d", lines, marathonLines), posX, posY, 10, rl.Gray)
+		DrawText(fmt.Sprintf("TIME: %s", formatTime(framesCounter)), posX, posY+15, 10, rl.Gray)
+	case modeSprint:
+		DrawText(fmt.Sprintf("GOAL: %d/%d", lines, sprintLines), posX, posY, 10, rl.Gray)
+		DrawText(formatTime(framesCounter), posX, posY+15, 20, rl.DarkGray)
+	case modeUltra:
+		DrawText("TIME LEFT:", posX, posY, 10, rl.Gray)
+		DrawText(formatTime(ultraFrames-framesCounter), posX, posY+15, 20, rl.DarkGray)
+	case modeZen:
+		DrawText("ZEN", posX, posY, 10, rl.Gray)
+		DrawText("[BACKSPACE] TO END", posX, posY+15, 10, rl.LightGray)
+	case modePuzzle:
+		drawPuzzleHUD(posX, posY)
+	}
+}
+
+// drawModeSelection draws the list of game modes, highlighting the selected one.
+func drawModeSelection() {
+	const title = "TETRIS"
+	DrawText(title, screenWidth/2-MeasureText(title, 40)/2, 60, 40, rl.DarkGray)
+
+	for mode := gameMode(0); mode < modeCount; mode++ {
+		posY := 120 + int(mode)*30
+		name := gameModes[mode].name
+
+		col := rl.LightGray
+		if mode == selectedMode {
+			col = rl.Maroon
+			name = "> " + name + " <"
+		}
+
+		DrawText(name, screenWidth/2-MeasureText(name, 20)/2, posY, 20, col)
+	}
+
+	description := gameModes[selectedMode].description
+	DrawText(description, screenWidth/2-MeasureText(description, 10)/2, 310, 10, rl.Gray)
+
+	if selectedMode == modePuzzle {
+		choice := "No puzzle fits this board and piece set."
+		if len(puzzles) > 0 {
+			choice = fmt.Sprintf("< %s: %s >", puzzles[puzzleIndex].name, puzzles[puzzleIndex].objective())
+		}
+
+		DrawText(choice, screenWidth/2-MeasureText(choice, 10)/2, 330, 10, rl.Maroon)
+	}
+
+	const startMsg = "PRESS [UP]/[DOWN] TO CHOOSE AND [ENTER] TO PLAY"
+	DrawText(startMsg, screenWidth/2-MeasureText(startMsg, 20)/2, 370, 20, rl.Gray)
+}
+
+// drawResults draws the result screen of the game that has just ended.
+func drawResults() {
+	var title, highlight string
+
+	switch {
+	case currentMode == modePuzzle:
+		title = "PUZZLE FAILED"
+		if gameOverResult == resultCompleted {
+			title = "PUZZLE SOLVED!"
+		}
+
+		highlight = fmt.Sprintf("FINESSE FAULTS: %d", finesseFaults)
+	case gameOverResult == resultTopOut:
+		title = "GAME OVER"
+		highlight = fmt.Sprintf("LINES: %d", lines)
+	case currentMode == modeSprint:
+		title = "SPRINT COMPLETE!"
+		highlight = formatTime(framesCounter)
+	case currentMode == modeUltra:
+		title = "TIME UP!"
+		highlight = fmt.Sprintf("SCORE: %d", score)
+	case currentMode == modeZen:
+		title = "SESSION ENDED"
+		highlight = fmt.Sprintf("LINES: %d", lines)
+	default:
+		title = "MARATHON COMPLETE!"
+		highlight = fmt.Sprintf("SCORE: %d", score)
+	}
+
+	DrawText(gameModes[currentMode].name, screenWidth/2-MeasureText(gameModes[currentMode].name, 20)/2, 60, 20, rl.Gray)
+	DrawText(title, screenWidth/2-MeasureText(title, 40)/2, 100, 40, rl.DarkGray)
+	DrawText(highlight, screenWidth/2-MeasureText(highlight, 30)/2, 160, 30, rl.Maroon)
+
+	details := fmt.Sprintf("SCORE: %d   LINES: %d   LEVEL: %d   TIME: %s", score, lines, level, formatTime(framesCounter))
+	DrawText(details, screenWidth/2-MeasureText(details, 10)/2, 220, 10, rl.Gray)
+
+	const replayMsg = "PRESS [ENTER] TO CHOOSE A MODE"
+	DrawText(replayMsg, screenWidth/2-MeasureText(replayMsg, 20)/2, screenHeight/2+80, 20, rl.Gray)
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	"image/color"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ------------------------------------------------------------------------------------
+// Piece sets
+// ------------------------------------------------------------------------------------
+//
+// The game plays with the seven tetrominoes by default, but any set of polyominoes can be loaded from a
+// data file instead (see the -pieces flag). The sets in the pieces directory are bundled with the game,
+// and pentominoes.txt explains the file format.
+//
+// NOTE: Whatever the set, the pieces are still "tetrominoes" for the rest of the game: a tetromino is
+//       the index of a piece in pieceSet. The SRS kick tables, the T-spins and the colours of the skins
+//       only apply to the standard set, the other ones use simpleKicks and the colours of the data file.
+
+const standardPieceSetName = "tetrominoes" // Name of the built-in set (see tetrominoSpawn)
+
+//go:embed pieces/*.txt
+var bundledPieceSets embed.FS
+
+// pieceDef describes a piece of a set: its spawn state (rotation0) and the size of the bounding box it rotates in.
+type pieceDef struct {
+	name  string
+	size  int        // Width and height of the bounding box.
+	cells []cell     // Squares of the piece in its spawn state, relative to the top-left corner of the box.
+	color color.RGBA // Colour of the piece, unused in the standard set (see skins.go).
+}
+
+// simpleKicks are the offsets (in grid coordinates) tried when rotating a piece that is not a standard tetromino:
+// the plain rotation, then one square to either side, one square up, and two squares to either side.
+var simpleKicks = []cell{{0, 0}, {+1, 0}, {-1, 0}, {0, -1}, {+2, 0}, {-2, 0}}
+
+// loadPieceSet makes the given set the one in play: the name of a bundled set, or the path to a data file.
+func loadPieceSet(name string) error {
+	if name == standardPieceSetName {
+		pieceSet = tetrominoSpawn[:]
+		isStandardPieceSet = true
+		return nil
+	}
+
+	data, err := bundledPieceSets.ReadFile("pieces/" + name + ".txt")
+	if err != nil {
+		if data, err = os.ReadFile(name); err != nil {
+			return fmt.Errorf("unknown piece set %q (valid: %s, or the path to a data file)", name, bundledPieceSetNames())
+		}
+	}
+
+	set, err := parsePieceSet(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	pieceSet = set
+	isStandardPieceSet = false
+
+	return nil
+}
+
+// bundledPieceSetNames lists the names of the piece sets that don't need a data file.
+func bundledPieceSetNames() string {
+	names := []string{standardPieceSetName}
+
+	entries, _ := bundledPieceSets.ReadDir("pieces")
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".txt"))
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// parsePieceSet reads a piece set from a data file (see pentominoes.txt for the format).
+func parsePieceSet(data []byte) ([]pieceDef, error) {
+	var (
+		set   []pieceDef
+		rows  []string // Rows of the piece being read
+		scale = 1
+
+		isPending bool // Whether the last piece of the set is still being read
+	)
+
+	// addPiece turns the rows read so far into the last piece of the set, once
+	addPiece := func() error {
+		if !isPending {
+			return nil
+		}
+
+		isPending = false
+
+		p := &set[len(set)-1]
+		if len(rows) == 0 {
+			return fmt.Errorf("piece %s has no rows", p.name)
+		}
+
+		for y, row := range rows {
+			if len(row) != len(rows) {
+				return fmt.Errorf("piece %s: the rows must form a square (%d rows, but row %d has %d cells)", p.name, len(rows), y+1, len(row))
+			}
+
+			for x, c := range row {
+				if c != 'X' {
+					continue
+				}
+
+				// Every square becomes a scale x scale block
+				for sy := 0; sy < scale; sy++ {
+					for sx := 0; sx < scale; sx++ {
+						p.cells = append(p.cells, cell{x: x*scale + sx, y: y*scale + sy})
+					}
+				}
+			}
+		}
+
+		if len(p.cells) == 0 {
+			return fmt.Errorf("piece %s has no squares", p.name)
+		}
+
+		p.size = len(rows) * scale
+		rows = rows[:0]
+
+		return nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(line)
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			// Blank lines and comments
+		case strings.Trim(line, "X.") == "":
+			if !isPending {
+				return nil, fmt.Errorf("line %d: a piece starts with a \"piece\" line", lineNumber)
+			}
+
+			rows = append(rows, line)
+		case fields[0] == "piece":
+			if err := addPiece(); err != nil {
+				return nil, err
+			}
+
+			if len(fields) != 5 {
+				return nil, fmt.Errorf("line %d: expected \"piece <name> <red> <green> <blue>\"", lineNumber)
+			}
+
+			var rgb [3]uint8
+			for k, field := range fields[2:] {
+				value, err := strconv.ParseUint(field, 10, 8)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid colour component %q (valid: 0-255)", lineNumber, field)
+				}
+
+				rgb[k] = uint8(value)
+			}
+
+			for _, p := range set {
+				if p.name == fields[1] {
+					return nil, fmt.Errorf("line %d: there is already a piece named %s", lineNumber, p.name)
+				}
+			}
+
+			set = append(set, pieceDef{name: fields[1], color: color.RGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 255}})
+			isPending = true
+		case fields[0] == "scale":
+			if err := addPiece(); err != nil {
+				return nil, err
+			}
+
+			var err error
+			if len(fields) != 2 {
+				err = fmt.Errorf("line %d: expected \"scale <factor>\"", lineNumber)
+			} else if scale, err = strconv.Atoi(fields[1]); err != nil || scale < 1 {
+				err = fmt.Errorf("line %d: invalid scale %q (valid: 1 or more)", lineNumber, fields[1])
+			}
+
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unexpected %q", lineNumber, line)
+		}
+	}
+
+	if err := addPiece(); err != nil {
+		return nil, err
+	}
+
+	if len(set) == 0 {
+		return nil, fmt.Errorf("no pieces found")
+	}
+
+	return set, nil
+}
+
+// maxPieceSize returns the size of the biggest bounding box of the piece set.
+func maxPieceSize() int {
+	size := 0
+	for _, p := range pieceSet {
+		if p.size > size {
+			size = p.size
+		}
+	}
+
+	return size
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePieceSet(t *testing.T) {
+	type piece struct {
+		name  string
+		size  int
+		cells []cell
+	}
+
+	tests := []struct {
+		name    string
+		lines   []string
+		want    []piece
+		wantErr bool
+	}{
+		{
+			name:  "one piece",
+			lines: []string{"# A comment", "piece A 1 2 3", ".X", "XX"},
+			want:  []piece{{"A", 2, []cell{{1, 0}, {0, 1}, {1, 1}}}},
+		},
+		{
+			name:  "scale",
+			lines: []string{"scale 2", "piece A 1 2 3", "X"},
+			want:  []piece{{"A", 2, []cell{{0, 0}, {1, 0}, {0, 1}, {1, 1}}}},
+		},
+		{
+			name:  "scale in the middle of the file",
+			lines: []string{"piece A 1 2 3", "XX", "XX", "scale 2", "piece B 1 2 3", "X"},
+			want: []piece{
+				{"A", 2, []cell{{0, 0}, {1, 0}, {0, 1}, {1, 1}}},
+				{"B", 2, []cell{{0, 0}, {1, 0}, {0, 1}, {1, 1}}},
+			},
+		},
+		{name: "no pieces", lines: []string{"# Nothing"}, wantErr: true},
+		{name: "rows before the first piece", lines: []string{"XX", "piece A 1 2 3", "X"}, wantErr: true},
+		{name: "rows after a scale line", lines: []string{"piece A 1 2 3", "X", "scale 2", "X"}, wantErr: true},
+		{name: "piece without rows", lines: []string{"piece A 1 2 3", "piece B 1 2 3", "X"}, wantErr: true},
+		{name: "piece without squares", lines: []string{"piece A 1 2 3", ".."}, wantErr: true},
+		{name: "rows that are not a square", lines: []string{"piece A 1 2 3", "XX"}, wantErr: true},
+		{name: "duplicate name", lines: []string{"piece A 1 2 3", "X", "piece A 4 5 6", "X"}, wantErr: true},
+		{name: "invalid colour", lines: []string{"piece A 1 2 300", "X"}, wantErr: true},
+		{name: "invalid scale", lines: []string{"scale 0", "piece A 1 2 3", "X"}, wantErr: true},
+		{name: "unexpected line", lines: []string{"piece A 1 2 3", "X", "rotate"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		set, err := parsePieceSet([]byte(strings.Join(tt.lines, "\n")))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: parsePieceSet() doesn't fail", tt.name)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: parsePieceSet(): %v", tt.name, err)
+			continue
+		}
+
+		var got []piece
+		for _, p := range set {
+			got = append(got, piece{p.name, p.size, p.cells})
+		}
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parsePieceSet() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBundledPieceSets(t *testing.T) {
+	entries, err := bundledPieceSets.ReadDir("pieces")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range entries {
+		data, err := bundledPieceSets.ReadFile("pieces/" + entry.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := parsePieceSet(data); err != nil {
+			t.Errorf("%s: %v", entry.Name(), err)
+		}
+	}
+}
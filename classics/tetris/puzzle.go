@@ -0,0 +1,443 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ------------------------------------------------------------------------------------
+// Puzzles (finesse trainer)
+// ------------------------------------------------------------------------------------
+//
+// A puzzle starts from a predefined board with a fixed sequence of pieces, and the player has to reach its
+// objective before running out of pieces: clear a number of lines, clear them with T-spins, or clear the whole
+// board (perfect clear). The puzzles in the puzzles directory are bundled with the game, another one can be
+// loaded from a data file (see the -puzzle flag), and tsd.txt explains the file format.
+//
+// While solving a puzzle the game also judges the finesse of the player: it counts the keys pressed to move
+// and rotate every piece, and compares them with the fewest inputs that could have put the piece there.
+// The extra inputs are wasted, and flashed next to the grid as a finesse fault.
+//
+// NOTE: The hold slot is disabled, the pieces come exactly in the order of the puzzle. Finesse only covers the
+//       pieces dropped from above, so the placements that need a soft drop (tucks and spins) are not judged.
+
+const timeToShowFinesseLabel = 90 // Number of frames the finesse verdict is shown after the piece is locked
+
+//go:embed puzzles/*.txt
+var bundledPuzzles embed.FS
+
+// puzzleGoal is the kind of objective of a puzzle.
+type puzzleGoal int
+
+const (
+	goalLines        puzzleGoal = iota // Clear a number of lines
+	goalTSpinLines                     // Clear a number of lines with T-spins
+	goalPerfectClear                   // Leave the board empty after clearing lines
+)
+
+// puzzle is a starting board, the pieces to play on it, and the objective to reach with them.
+type puzzle struct {
+	name   string      // Name shown in the mode selector.
+	source string      // Name of the bundled puzzle, or path to its data file (see loadPuzzle).
+	goal   puzzleGoal  // Kind of objective.
+	lines  int         // Number of lines to clear (unused for a perfect clear).
+	pieces []tetromino // Sequence of pieces, in order.
+	board  []string    // Rows of the starting board ('X' for a square), the last one at the bottom of the grid.
+}
+
+// objective describes the objective of the puzzle to the player.
+func (p puzzle) objective() string {
+	switch p.goal {
+	case goalTSpinLines:
+		return fmt.Sprintf("Clear %d lines with T-spins in %d pieces.", p.lines, len(p.pieces))
+	case goalPerfectClear:
+		return fmt.Sprintf("Perfect clear in %d pieces.", len(p.pieces))
+	default:
+		return fmt.Sprintf("Clear %d lines in %d pieces.", p.lines, len(p.pieces))
+	}
+}
+
+// sequenceRandomizer deals the pieces of a puzzle in order, and then noTetromino once they run out.
+type sequenceRandomizer struct {
+	pieces []tetromino
+	dealt  int
+}
+
+func (r *sequenceRandomizer) next() tetromino {
+	if r.dealt >= len(r.pieces) {
+		return noTetromino
+	}
+
+	r.dealt++
+
+	return r.pieces[r.dealt-1]
+}
+
+// loadPuzzles makes the list of puzzles the player can choose from: the one in the given file (if any),
+// followed by the bundled ones.
+// NOTE: The bundled puzzles are made for the standard board and pieces, the ones that don't fit are left out.
+func loadPuzzles(path string) error {
+	puzzles = puzzles[:0]
+
+	if path != "" {
+		p, err := loadPuzzle(path)
+		if err != nil {
+			return err
+		}
+
+		puzzles = append(puzzles, p)
+	}
+
+	entries, _ := bundledPuzzles.ReadDir("puzzles")
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".txt")
+		if name == path {
+			continue // Already loaded
+		}
+
+		if p, err := loadPuzzle(name); err == nil {
+			puzzles = append(puzzles, p)
+		}
+	}
+
+	return nil
+}
+
+// loadPuzzle reads a puzzle: the name of a bundled puzzle, or the path to a data file.
+// The puzzle must fit the board, and its pieces must be part of the piece set in play.
+func loadPuzzle(name string) (puzzle, error) {
+	data, err := bundledPuzzles.ReadFile("puzzles/" + name + ".txt")
+	if err != nil {
+		if data, err = os.ReadFile(name); err != nil {
+			return puzzle{}, fmt.Errorf("unknown puzzle %q (valid: %s, or the path to a data file)", name, bundledPuzzleNames())
+		}
+	}
+
+	p, err := parsePuzzle(data)
+	if err != nil {
+		return p, fmt.Errorf("%s: %w", name, err)
+	}
+
+	p.source = name
+
+	return p, nil
+}
+
+// bundledPuzzleNames lists the names of the puzzles that don't need a data file.
+func bundledPuzzleNames() string {
+	var names []string
+
+	entries, _ := bundledPuzzles.ReadDir("puzzles")
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".txt"))
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// parsePuzzle reads a puzzle from a data file (see tsd.txt for the format).
+func parsePuzzle(data []byte) (puzzle, error) {
+	var (
+		p       puzzle
+		hasGoal bool
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(line)
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			// Blank lines and comments
+		case strings.Trim(line, "X.") == "":
+			if len(line) != gridSizeX-2 {
+				return p, fmt.Errorf("line %d: the board has %d columns, but the row has %d", lineNumber, gridSizeX-2, len(line))
+			}
+
+			if !strings.Contains(line, ".") {
+				return p, fmt.Errorf("line %d: the row is already complete", lineNumber)
+			}
+
+			p.board = append(p.board, line)
+		case fields[0] == "name":
+			p.name = strings.TrimSpace(strings.TrimPrefix(line, "name"))
+		case fields[0] == "goal":
+			var err error
+			switch {
+			case len(fields) == 2 && fields[1] == "perfect":
+				p.goal = goalPerfectClear
+			case len(fields) == 3 && (fields[1] == "lines" || fields[1] == "tspin"):
+				p.goal = goalLines
+				if fields[1] == "tspin" {
+					p.goal = goalTSpinLines
+				}
+
+				if p.lines, err = strconv.Atoi(fields[2]); err != nil || p.lines < 1 {
+					err = fmt.Errorf("line %d: invalid number of lines %q (valid: 1 or more)", lineNumber, fields[2])
+				}
+			default:
+				err = fmt.Errorf("line %d: expected \"goal lines <n>\", \"goal tspin <n>\" or \"goal perfect\"", lineNumber)
+			}
+
+			if err != nil {
+				return p, err
+			}
+
+			hasGoal = true
+		case fields[0] == "pieces":
+			for _, name := range fields[1:] {
+				kind, err := findPiece(name)
+				if err != nil {
+					return p, fmt.Errorf("line %d: %w", lineNumber, err)
+				}
+
+				p.pieces = append(p.pieces, kind)
+			}
+		default:
+			return p, fmt.Errorf("line %d: unexpected %q", lineNumber, line)
+		}
+	}
+
+	switch {
+	case p.name == "":
+		return p, fmt.Errorf("the puzzle has no name")
+	case !hasGoal:
+		return p, fmt.Errorf("the puzzle has no goal")
+	case len(p.pieces) == 0:
+		return p, fmt.Errorf("the puzzle has no pieces")
+	case len(p.board) > gridSizeY-4:
+		// The top two rows must stay empty, or the game is over (see topOut)
+		return p, fmt.Errorf("the board has %d rows, the puzzle can't have more than %d", gridSizeY-2, gridSizeY-4)
+	}
+
+	return p, nil
+}
+
+// findPiece returns the piece of the set with the given name.
+func findPiece(name string) (tetromino, error) {
+	names := make([]string, len(pieceSet))
+	for kind, def := range pieceSet {
+		if def.name == name {
+			return tetromino(kind), nil
+		}
+
+		names[kind] = def.name
+	}
+
+	return noTetromino, fmt.Errorf("unknown piece %q (valid: %s)", name, strings.Join(names, ", "))
+}
+
+// startPuzzle sets up the board and the pieces of the given puzzle.
+// NOTE: It must be called after reset(), which empties the grid.
+func startPuzzle(p puzzle) {
+	top := gridSizeY - 1 - len(p.board)
+
+	for y, row := range p.board {
+		for x, square := range row {
+			if square == 'X' {
+				grid[x+1][top+y] = FULL
+				gridKind[x+1][top+y] = garbageKind
+				gridPiece[x+1][top+y] = 0
+			}
+		}
+	}
+
+	pieceRandomizer = &sequenceRandomizer{pieces: p.pieces}
+
+	puzzleLines = 0
+	isPerfectClear = false
+	finesseFaults = 0
+	finesseLabelCounter = 0
+}
+
+// updatePuzzle keeps track of the objective, when lines are cleared by a piece or by a chain (see countClearedLines).
+// NOTE: It must be called once the completed lines have been marked (FADING).
+func updatePuzzle(clearedLines int, spin tSpin) {
+	if puzzles[puzzleIndex].goal != goalTSpinLines || spin != tSpinNone {
+		puzzleLines += clearedLines
+	}
+
+	// Every square left is in a completed line
+	isPerfectClear = clearedLines > 0
+	for j := 0; j < gridSizeY-1 && isPerfectClear; j++ {
+		for i := 1; i < gridSizeX-1; i++ {
+			if grid[i][j] == FULL {
+				isPerfectClear = false
+				break
+			}
+		}
+	}
+}
+
+// isPuzzleSolved reports whether the objective of the puzzle has been reached.
+func isPuzzleSolved() bool {
+	if puzzles[puzzleIndex].goal == goalPerfectClear {
+		return isPerfectClear
+	}
+
+	return puzzleLines >= puzzles[puzzleIndex].lines
+}
+
+// isPuzzleOver reports whether all the pieces of the puzzle have been played.
+func isPuzzleOver() bool {
+	return !isPieceFalling && (len(nextQueue) == 0 || nextQueue[0] == noTetromino)
+}
+
+// countFinesseInputs counts the keys pressed to move and rotate the active piece during this frame.
+// NOTE: Holding a key counts once, so shifting the piece to the wall with the DAS is a single input.
+func countFinesseInputs() {
+	for _, key := range [...]int32{rl.KeyLeft, rl.KeyRight, rl.KeyUp, rl.KeyX, rl.KeyZ, rl.KeyA} {
+		if isKeyPressed(key) {
+			finesseInputs++
+		}
+	}
+
+	if isKeyDown(rl.KeyDown) {
+		hasSoftDropped = true
+	}
+}
+
+// judgeFinesse compares the inputs spent on the piece being locked with the fewest ones needed,
+// and counts the wasted ones as finesse faults.
+func judgeFinesse() {
+	if hasSoftDropped {
+		return
+	}
+
+	optimal := optimalFinesse(pieceKind, pieceRotation, piecePosX)
+	if optimal < 0 {
+		return
+	}
+
+	finesseLabel = "FINESSE OK"
+	if wasted := finesseInputs - optimal; wasted > 0 {
+		finesseFaults += wasted
+		finesseLabel = "FINESSE FAULT"
+	}
+
+	finesseLabel += fmt.Sprintf("\n%d INPUTS (%d)", finesseInputs, optimal)
+	finesseLabelCounter = timeToShowFinesseLabel
+}
+
+// optimalFinesse returns the fewest inputs that take the piece from its spawn state to a placement covering the
+// same columns as the given one (the piece is then dropped): one per tap, shift to the wall (DAS) or rotation.
+// The stack doesn't matter, so the search is done on an empty board.
+// It returns -1 if no such placement can be reached.
+func optimalFinesse(kind tetromino, rotation rotationState, x int) int {
+	board := botBoard(newMatrix[bool](gridSizeX, gridSizeY))
+	for j := 0; j < gridSizeY; j++ {
+		board[0][j], board[gridSizeX-1][j] = true, true
+	}
+
+	for i := 0; i < gridSizeX; i++ {
+		board[i][gridSizeY-1] = true
+	}
+
+	target := footprint(kind, rotation, x)
+
+	// Breadth-first search, every move costs one input
+	start := placement{rotation: rotation0, x: tetrominoSpawnX(kind), y: 0}
+	inputs := map[placement]int{start: 0}
+
+	for queue := []placement{start}; len(queue) > 0; queue = queue[1:] {
+		p := queue[0]
+		if footprint(kind, p.rotation, p.x) == target {
+			return inputs[p]
+		}
+
+		var moves []placement
+
+		for _, step := range [2]int{-1, 1} {
+			if board.fits(kind, p.rotation, p.x+step, p.y) {
+				moves = append(moves, placement{rotation: p.rotation, x: p.x + step, y: p.y})
+			}
+
+			wall := p
+			for board.fits(kind, wall.rotation, wall.x+step, wall.y) {
+				wall.x += step
+			}
+
+			moves = append(moves, wall)
+		}
+
+		for _, direction := range [...]rotationDirection{rotateClockwise, rotate180, rotateCounterClockwise} {
+			if rotated, ok := board.rotate(kind, p, direction); ok {
+				moves = append(moves, rotated)
+			}
+		}
+
+		for _, move := range moves {
+			if _, seen := inputs[move]; !seen {
+				inputs[move] = inputs[p] + 1
+				queue = append(queue, move)
+			}
+		}
+	}
+
+	return -1
+}
+
+// footprint describes the squares of the piece at the given column, up to a vertical shift,
+// so that two placements leaving the same squares once dropped have the same footprint.
+func footprint(kind tetromino, rotation rotationState, x int) string {
+	cells := append([]cell(nil), tetrominoCells(kind, rotation)...)
+
+	top := cells[0].y
+	for _, c := range cells {
+		if c.y < top {
+			top = c.y
+		}
+	}
+
+	for k := range cells {
+		cells[k].x += x
+		cells[k].y -= top
+	}
+
+	sort.Slice(cells, func(a, b int) bool {
+		return cells[a].y < cells[b].y || (cells[a].y == cells[b].y && cells[a].x < cells[b].x)
+	})
+
+	return fmt.Sprint(cells)
+}
+
+// drawPuzzleHUD draws the progress towards the objective of the puzzle and the finesse faults.
+func drawPuzzleHUD(posX, posY int32) {
+	p := puzzles[puzzleIndex]
+
+	goal := "GOAL: PERFECT CLEAR"
+	switch p.goal {
+	case goalLines:
+		goal = fmt.Sprintf("GOAL: %d/%d LINES", puzzleLines, p.lines)
+	case goalTSpinLines:
+		goal = fmt.Sprintf("GOAL: %d/%d T-SPIN LINES", puzzleLines, p.lines)
+	}
+
+	DrawText(p.name, posX, posY, 10, rl.DarkGray)
+	DrawText(goal, posX, posY+15, 10, rl.Gray)
+	DrawText(fmt.Sprintf("PIECES: %d/%d", spawnCounter, len(p.pieces)), posX, posY+30, 10, rl.Gray)
+	DrawText(fmt.Sprintf("FINESSE FAULTS: %d", finesseFaults), posX, posY+45, 10, rl.Gray)
+}
+
+// drawFinesseLabel flashes the finesse verdict on the last piece locked.
+func drawFinesseLabel(posX, posY int32) {
+	if finesseLabelCounter <= 0 {
+		return
+	}
+
+	col := rl.Gray
+	if strings.HasPrefix(finesseLabel, "FINESSE FAULT") && finesseLabelCounter%8 < 4 {
+		col = rl.Maroon
+	}
+
+	DrawText(finesseLabel, posX, posY, 10, col)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePuzzle(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []string
+		want    puzzle
+		wantErr bool
+	}{
+		{
+			name:  "T-spin double",
+			lines: []string{"# A comment", "name TSD", "goal tspin 2", "pieces T", "", "XX........", "X...XXXXXX", "XX.XXXXXXX"},
+			want: puzzle{name: "TSD", goal: goalTSpinLines, lines: 2, pieces: []tetromino{tetrominoT},
+				board: []string{"XX........", "X...XXXXXX", "XX.XXXXXXX"}},
+		},
+		{
+			name:  "perfect clear without a board",
+			lines: []string{"name PC", "goal perfect", "pieces I O", "pieces L"},
+			want:  puzzle{name: "PC", goal: goalPerfectClear, pieces: []tetromino{tetrominoI, tetrominoO, tetrominoL}},
+		},
+		{name: "no name", lines: []string{"goal lines 1", "pieces I"}, wantErr: true},
+		{name: "no goal", lines: []string{"name A", "pieces I"}, wantErr: true},
+		{name: "no pieces", lines: []string{"name A", "goal lines 1"}, wantErr: true},
+		{name: "unknown piece", lines: []string{"name A", "goal lines 1", "pieces I Q"}, wantErr: true},
+		{name: "invalid goal", lines: []string{"name A", "goal lines 0", "pieces I"}, wantErr: true},
+		{name: "unknown goal", lines: []string{"name A", "goal tetris", "pieces I"}, wantErr: true},
+		{name: "row too short", lines: []string{"name A", "goal lines 1", "pieces I", "X........"}, wantErr: true},
+		{name: "complete row", lines: []string{"name A", "goal lines 1", "pieces I", "XXXXXXXXXX"}, wantErr: true},
+		{name: "unexpected line", lines: []string{"name A", "goal lines 1", "pieces I", "hold I"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parsePuzzle([]byte(strings.Join(tt.lines, "\n")))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: parsePuzzle() doesn't fail", tt.name)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: parsePuzzle(): %v", tt.name, err)
+		} else if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parsePuzzle() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// The top two rows of the grid must stay empty.
+func TestParsePuzzleRejectsHighBoards(t *testing.T) {
+	lines := []string{"name A", "goal lines 1", "pieces I"}
+	for len(lines) < 3+gridSizeY-3 {
+		lines = append(lines, ".XXXXXXXXX")
+	}
+
+	if _, err := parsePuzzle([]byte(strings.Join(lines, "\n"))); err == nil {
+		t.Errorf("parsePuzzle() of a board of %d rows doesn't fail", len(lines)-3)
+	}
+}
+
+func TestBundledPuzzles(t *testing.T) {
+	entries, err := bundledPuzzles.ReadDir("puzzles")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range entries {
+		if _, err := loadPuzzle(strings.TrimSuffix(entry.Name(), ".txt")); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestOptimalFinesse(t *testing.T) {
+	// On the standard board, the T and the I spawn with their left column at x = 4 (see tetrominoSpawnX)
+	tests := []struct {
+		name     string
+		kind     tetromino
+		rotation rotationState
+		x        int
+		want     int
+	}{
+		{"dropped at once", tetrominoT, rotation0, 4, 0},
+		{"one tap", tetrominoT, rotation0, 3, 1},
+		{"shifted to the wall", tetrominoT, rotation0, 1, 1},
+		{"one tap off the wall", tetrominoT, rotation0, 2, 2},
+		{"rotated", tetrominoT, rotationR, 4, 1},
+		{"rotated 180°", tetrominoT, rotation2, 4, 1},
+		{"rotated against the wall", tetrominoT, rotationL, 1, 2},
+		{"vertical I against the right wall", tetrominoI, rotationR, 8, 2},
+		{"same squares, other rotation", tetrominoI, rotationL, 9, 2},
+		{"out of the board", tetrominoT, rotation0, 9, -1},
+	}
+
+	for _, tt := range tests {
+		if got := optimalFinesse(tt.kind, tt.rotation, tt.x); got != tt.want {
+			t.Errorf("%s: optimalFinesse(%s, %d, %d) = %d, want %d",
+				tt.name, tetrominoSpawn[tt.kind].name, tt.rotation, tt.x, got, tt.want)
+		}
+	}
+}
+
+// Once the pieces of a puzzle run out, the puzzle ends instead of spawning another piece.
+func TestCreatePieceEndsThePuzzle(t *testing.T) {
+	puzzles = []puzzle{{name: "A", goal: goalLines, lines: 1, pieces: []tetromino{tetrominoI}}}
+	puzzleIndex, puzzleLines = 0, 0
+	currentMode, isGameover, isFirst, isPieceFalling = modePuzzle, false, false, false
+	nextQueue = []tetromino{noTetromino, noTetromino}
+
+	if CreatePiece() || !isGameover || gameOverResult != resultFailed {
+		t.Errorf("the puzzle goes on (game over: %v, result %d)", isGameover, gameOverResult)
+	}
+
+	currentMode, isGameover, puzzles = modeMarathon, false, nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ------------------------------------------------------------------------------------
+// Randomizers
+// ------------------------------------------------------------------------------------
+
+// randomizer decides the sequence of tetrominoes the player receives.
+type randomizer interface {
+	next() tetromino
+}
+
+// randomizers lists the available randomizers by the name used to select them (see the -randomizer flag).
+var randomizers = map[string]func() randomizer{
+	"bag7":    func() randomizer { return &bagRandomizer{copies: 1} },
+	"bag14":   func() randomizer { return &bagRandomizer{copies: 2} },
+	"classic": func() randomizer { return memorylessRandomizer{} },
+	"tgm":     func() randomizer { return newHistoryRandomizer() },
+}
+
+// newRandomizer returns the randomizer registered under the given name.
+func newRandomizer(name string) (randomizer, error) {
+	create, ok := randomizers[name]
+	if !ok {
+		names := make([]string, 0, len(randomizers))
+		for n := range randomizers {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		return nil, fmt.Errorf("unknown randomizer %q (valid: %s)", name, strings.Join(names, ", "))
+	}
+
+	return create(), nil
+}
+
+// randomTetromino returns any of the pieces of the set with the same probability.
+func randomTetromino() tetromino {
+	return tetromino(rl.GetRandomValue(0, int32(len(pieceSet))-1))
+}
+
+// memorylessRandomizer is the classic randomizer: every piece is picked independently of the previous ones,
+// so long droughts (e.g. no I piece for 30 pieces) are possible.
+type memorylessRandomizer struct{}
+
+func (memorylessRandomizer) next() tetromino {
+	return randomTetromino()
+}
+
+// bagRandomizer puts a number of copies of each tetromino in a bag, shuffles it and deals the pieces in
+// that order, refilling the bag once it is empty. With one copy (7-bag) the player never waits more than
+// 12 pieces for a specific tetromino.
+type bagRandomizer struct {
+	copies int
+	bag    []tetromino
+}
+
+func (b *bagRandomizer) next() tetromino {
+	if len(b.bag) == 0 {
+		for c := 0; c < b.copies; c++ {
+			for kind := range pieceSet {
+				b.bag = append(b.bag, tetromino(kind))
+			}
+		}
+
+		// Fisher-Yates shuffle
+		for i := len(b.bag) - 1; i > 0; i-- {
+			j := rl.GetRandomValue(0, int32(i))
+			b.bag[i], b.bag[j] = b.bag[j], b.bag[i]
+		}
+	}
+
+	kind := b.bag[0]
+	b.bag = b.bag[1:]
+
+	return kind
+}
+
+// historyRandomizer is the TGM-style randomizer: it remembers the last four pieces dealt and re-rolls
+// (up to a fixed number of times) whenever it picks one of them.
+type historyRandomizer struct {
+	history [4]tetromino
+	rolls   int
+	isFirst bool
+}
+
+func newHistoryRandomizer() *historyRandomizer {
+	// NOTE: With a custom piece set (see pieces.go) there are no S and Z pieces, so the history starts empty
+	if !isStandardPieceSet {
+		return &historyRandomizer{
+			history: [4]tetromino{noTetromino, noTetromino, noTetromino, noTetromino},
+			rolls:   6,
+		}
+	}
+
+	return &historyRandomizer{
+		history: [4]tetromino{tetrominoZ, tetrominoS, tetrominoS, tetrominoZ},
+		rolls:   6,
+		isFirst: true,
+	}
+}
+
+func (h *historyRandomizer) next() tetromino {
+	var kind tetromino
+
+	if h.isFirst {
+		// The first piece is never an S, Z or O, so the game never starts with a forced overhang.
+		h.isFirst = false
+
+		for kind = randomTetromino(); kind == tetrominoS || kind == tetrominoZ || kind == tetrominoO; {
+			kind = randomTetromino()
+		}
+	} else {
+		for roll := 0; roll < h.rolls; roll++ {
+			kind = randomTetromino()
+			if !h.inHistory(kind) {
+				break
+			}
+		}
+	}
+
+	copy(h.history[1:], h.history[:len(h.history)-1])
+	h.history[0] = kind
+
+	return kind
+}
+
+func (h *historyRandomizer) inHistory(kind tetromino) bool {
+	for _, k := range h.history {
+		if k == kind {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,75 @@
+package main
+
+import "testing"
+
+func TestNewRandomizer(t *testing.T) {
+	for _, name := range []string{"bag7", "bag14", "classic", "tgm"} {
+		if _, err := newRandomizer(name); err != nil {
+			t.Errorf("newRandomizer(%q): %v", name, err)
+		}
+	}
+
+	if _, err := newRandomizer("bag8"); err == nil {
+		t.Error("newRandomizer(\"bag8\") doesn't fail")
+	}
+}
+
+// Every bag deals each tetromino as many times as there are copies of it in the bag.
+func TestBagRandomizer(t *testing.T) {
+	tests := []struct {
+		name   string
+		copies int
+	}{
+		{"bag7", 1},
+		{"bag14", 2},
+	}
+
+	for _, tt := range tests {
+		r := &bagRandomizer{copies: tt.copies}
+		bagSize := tt.copies * int(tetrominoCount)
+
+		for bag := 0; bag < 10; bag++ {
+			var dealt [tetrominoCount]int
+			for k := 0; k < bagSize; k++ {
+				dealt[r.next()]++
+			}
+
+			for kind, n := range dealt {
+				if n != tt.copies {
+					t.Fatalf("%s: bag %d dealt %s %d times, want %d", tt.name, bag, tetrominoSpawn[kind].name, n, tt.copies)
+				}
+			}
+		}
+	}
+}
+
+func TestHistoryRandomizer(t *testing.T) {
+	for game := 0; game < 50; game++ {
+		r := newHistoryRandomizer()
+
+		if first := r.next(); first == tetrominoS || first == tetrominoZ || first == tetrominoO {
+			t.Fatalf("game %d starts with %s", game, tetrominoSpawn[first].name)
+		}
+
+		// The last piece dealt is always the first one of the history
+		for k := 0; k < 20; k++ {
+			kind := r.next()
+			if kind < 0 || kind >= tetrominoCount {
+				t.Fatalf("game %d: invalid piece %d", game, kind)
+			}
+
+			if r.history[0] != kind || !r.inHistory(kind) {
+				t.Fatalf("game %d: %s is not in the history %v", game, tetrominoSpawn[kind].name, r.history)
+			}
+		}
+	}
+}
+
+func TestHistoryRandomizerStartsWithSZ(t *testing.T) {
+	r := newHistoryRandomizer()
+
+	want := [4]tetromino{tetrominoZ, tetrominoS, tetrominoS, tetrominoZ}
+	if r.history != want || !r.isFirst {
+		t.Errorf("history = %v (first: %v), want %v", r.history, r.isFirst, want)
+	}
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ------------------------------------------------------------------------------------
+// Replays
+// ------------------------------------------------------------------------------------
+//
+// The game logic only depends on the settings, on the random numbers and on the keys polled every frame
+// (see input.go). So a replay only records the settings, the seed of the random number generator, and the
+// input of every frame: playing it back feeds the same input to UpdateGame, which reproduces the game exactly.
+//
+// The replay file is binary, with every number written as a varint:
+//
+//	"TTRP", version, seed, game mode, settings (see replay), number of players,
+//	and for every player: handling (DAS, ARR, SDF), number of frames,
+//	then runs of identical input (frames in the run, keys held, keys pressed)
+//
+// NOTE: A replay played with a custom piece set (see pieces.go) or a puzzle loaded from a file (see puzzle.go)
+//       needs the same data file to be played back.
+
+const (
+	replayMagic   = "TTRP" // First bytes of a replay file
+	replayVersion = 1      // Version of the replay file format
+
+	maxReplaySpeed  = 8                 // Fastest playback speed, in frames played per frame drawn
+	maxReplayFrames = 24 * 60 * 60 * 60 // Longest replay that can be loaded, in frames (a whole day of play)
+)
+
+// replay holds everything needed to play a game again.
+type replay struct {
+	seed uint32
+	mode gameMode
+
+	// Settings
+	randomizer      string
+	lockReset       string
+	gravity         string
+	pieces          string
+	width, height   int
+	previewCount    int
+	lockDelayFrames int
+	puzzle          string // Source of the puzzle (see puzzle), in puzzle mode.
+
+	handlings []handling     // Handling of every player (two players in versus mode).
+	inputs    [][]inputState // Input of every frame, for every player.
+}
+
+// lockResetName returns the name of the given lock reset mode (see lockResetModes).
+func lockResetName(mode lockResetMode) string {
+	for name, m := range lockResetModes {
+		if m == mode {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// startRecording seeds the random number generator and starts recording the game that is about to begin.
+// NOTE: It must be called before reset(), so that the randomizers are seeded as well.
+func startRecording() {
+	seed := uint32(time.Now().UnixNano())
+	rl.SetRandomSeed(seed)
+
+	if recordPath == "" {
+		return
+	}
+
+	players := 1
+	if selectedMode == modeVersus {
+		players = 2
+	}
+
+	recording = replay{
+		seed:            seed,
+		mode:            selectedMode,
+		randomizer:      randomizerName,
+		lockReset:       lockResetName(lockReset),
+		gravity:         clearGravityName(lineClearGravity),
+		pieces:          pieceSetName,
+		width:           gridSizeX - 2,
+		height:          gridSizeY - 2,
+		previewCount:    previewCount,
+		lockDelayFrames: lockDelayFrames,
+		handlings:       append([]handling(nil), handlings[:players]...),
+		inputs:          make([][]inputState, players),
+	}
+
+	if selectedMode == modePuzzle {
+		recording.puzzle = puzzles[puzzleIndex].source
+	}
+
+	isRecording = true
+}
+
+// recordInput adds the input of the given player during this frame to the recording, if any.
+func recordInput(player int, state inputState) {
+	if isRecording {
+		recording.inputs[player] = append(recording.inputs[player], state)
+	}
+}
+
+// isGameFinished reports whether the game being played (or watched) has ended.
+func isGameFinished() bool {
+	if currentMode == modeVersus {
+		return versusWinner != noWinner
+	}
+
+	return isGameover
+}
+
+// updateReplays saves the recording once the game is over, and stops the playback at the end of the replay.
+func updateReplays() {
+	if isRecording && isGameFinished() {
+		isRecording = false
+
+		if err := recording.save(recordPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+
+	if isReplaying && (isGameFinished() || isPlaybackOver()) {
+		isReplaying = false
+	}
+}
+
+// startPlayback starts watching the given replay.
+// NOTE: The settings of the replay must have been applied already (see main).
+func startPlayback(r replay) {
+	playback = r
+	playbackFrames = [2]int{}
+	playbackSpeed = 1
+	isPlaybackPaused = false
+	isReplaying = true
+
+	rl.SetRandomSeed(r.seed)
+
+	selectedMode = r.mode
+	currentMode = r.mode
+	isSelectingMode = false
+	isGameover = false
+
+	reset()
+
+	switch currentMode {
+	case modeVersus:
+		startVersus()
+	case modePuzzle:
+		// The puzzle of the replay is the first one of the list (see main)
+		puzzleIndex = 0
+		startPuzzle(puzzles[puzzleIndex])
+	}
+}
+
+// replayInput returns the recorded input of the given player for this frame.
+func replayInput(player int) inputState {
+	if player >= len(playback.inputs) || playbackFrames[player] >= len(playback.inputs[player]) {
+		return inputState{}
+	}
+
+	state := playback.inputs[player][playbackFrames[player]]
+	playbackFrames[player]++
+
+	return state
+}
+
+// isPlaybackOver reports whether all the recorded input has been played back.
+func isPlaybackOver() bool {
+	for player, inputs := range playback.inputs {
+		if playbackFrames[player] < len(inputs) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// updatePlaybackControls lets the player pause the replay with [SPACE], change its speed with [F],
+// and advance it frame by frame with [RIGHT] while paused.
+// It returns the number of frames of the replay to play during this frame.
+func updatePlaybackControls() int {
+	if rl.IsKeyPressed(rl.KeySpace) {
+		isPlaybackPaused = !isPlaybackPaused
+	}
+
+	if rl.IsKeyPressed(rl.KeyF) {
+		playbackSpeed *= 2
+		if playbackSpeed > maxReplaySpeed {
+			playbackSpeed = 1
+		}
+	}
+
+	if isPlaybackPaused {
+		if rl.IsKeyPressed(rl.KeyRight) {
+			return 1
+		}
+
+		return 0
+	}
+
+	return playbackSpeed
+}
+
+// drawPlaybackHUD draws the state of the replay being watched, at the bottom of the screen.
+func drawPlaybackHUD() {
+	if !isReplaying {
+		return
+	}
+
+	status := fmt.Sprintf("REPLAY %dx - %s", playbackSpeed, formatTime(playbackFrames[0]))
+	if isPlaybackPaused {
+		status = fmt.Sprintf("REPLAY PAUSED - %s [RIGHT] NEXT FRAME", formatTime(playbackFrames[0]))
+	}
+
+	status += "   [SPACE] PAUSE   [F] SPEED"
+	DrawText(status, screenWidth/2-MeasureText(status, 10)/2, screenHeight-15, 10, rl.Maroon)
+}
+
+// save writes the replay to the given file.
+func (r replay) save(path string) error {
+	var buf bytes.Buffer
+
+	putNumber := func(n int) {
+		var b [binary.MaxVarintLen64]byte
+		buf.Write(b[:binary.PutUvarint(b[:], uint64(n))])
+	}
+
+	putString := func(s string) {
+		putNumber(len(s))
+		buf.WriteString(s)
+	}
+
+	buf.WriteString(replayMagic)
+	putNumber(replayVersion)
+	putNumber(int(r.seed))
+	putNumber(int(r.mode))
+
+	putString(r.randomizer)
+	putString(r.lockReset)
+	putString(r.gravity)
+	putString(r.pieces)
+	putNumber(r.width)
+	putNumber(r.height)
+	putNumber(r.previewCount)
+	putNumber(r.lockDelayFrames)
+	putString(r.puzzle)
+
+	// The input rarely changes from one frame to the next, so it's stored as runs of identical input
+	putNumber(len(r.inputs))
+	for player, inputs := range r.inputs {
+		putNumber(r.handlings[player].das)
+		putNumber(r.handlings[player].arr)
+		putNumber(r.handlings[player].sdf)
+		putNumber(len(inputs))
+
+		for k := 0; k < len(inputs); {
+			run := 1
+			for k+run < len(inputs) && inputs[k+run] == inputs[k] {
+				run++
+			}
+
+			putNumber(run)
+			putNumber(int(inputs[k].down))
+			putNumber(int(inputs[k].pressed))
+
+			k += run
+		}
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("saving the replay: %w", err)
+	}
+
+	return nil
+}
+
+// loadReplay reads a replay from the given file.
+func loadReplay(path string) (replay, error) {
+	var r replay
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return r, err
+	}
+
+	if !bytes.HasPrefix(data, []byte(replayMagic)) {
+		return r, fmt.Errorf("%s is not a replay file", path)
+	}
+
+	reader := bytes.NewReader(data[len(replayMagic):])
+
+	// The first error is kept, and every read after it returns 0
+	getNumber := func() int {
+		if err != nil {
+			return 0
+		}
+
+		var n uint64
+		n, err = binary.ReadUvarint(reader)
+
+		return int(n)
+	}
+
+	// A corrupted length can't make us allocate more than what is left in the file
+	getString := func() string {
+		n := getNumber()
+		if err == nil && (n < 0 || n > reader.Len()) {
+			err = errors.New("invalid string length")
+		}
+		if err != nil {
+			return ""
+		}
+
+		s := make([]byte, n)
+		_, err = io.ReadFull(reader, s)
+
+		return string(s)
+	}
+
+	if version := getNumber(); err == nil && version != replayVersion {
+		return r, fmt.Errorf("%s: unsupported replay version %d (valid: %d)", path, version, replayVersion)
+	}
+
+	r.seed = uint32(getNumber())
+	r.mode = gameMode(getNumber())
+
+	r.randomizer = getString()
+	r.lockReset = getString()
+	r.gravity = getString()
+	r.pieces = getString()
+	r.width = getNumber()
+	r.height = getNumber()
+	r.previewCount = getNumber()
+	r.lockDelayFrames = getNumber()
+	r.puzzle = getString()
+
+	players := getNumber()
+	if err == nil && (players < 1 || players > len(playbackFrames) || r.mode < 0 || r.mode >= modeCount || (r.mode == modePuzzle && r.puzzle == "")) {
+		err = errors.New("invalid header")
+	}
+
+	if err == nil && (r.width < minBoardWidth || r.width > maxBoardWidth || r.height < minBoardHeight || r.height > maxBoardHeight ||
+		r.previewCount < 1 || r.previewCount > maxPreviewCount || r.lockDelayFrames < 1) {
+		err = errors.New("invalid settings")
+	}
+
+	for player := 0; player < players && err == nil; player++ {
+		h := handling{das: getNumber(), arr: getNumber(), sdf: getNumber()}
+		if err == nil && (h.das < 0 || h.arr < 0 || h.sdf < 1) {
+			err = errors.New("invalid handling")
+		}
+
+		r.handlings = append(r.handlings, h)
+
+		// NOTE: The number of frames comes from the file, so the inputs grow as the runs are read instead of
+		//       being allocated up front
+		frames := getNumber()
+		if err == nil && (frames < 0 || frames > maxReplayFrames) {
+			err = errors.New("invalid number of frames")
+		}
+
+		var inputs []inputState
+
+		for len(inputs) < frames && err == nil {
+			run := getNumber()
+			state := inputState{down: uint32(getNumber()), pressed: uint32(getNumber())}
+
+			if run < 1 || len(inputs)+run > frames {
+				err = errors.New("invalid input run")
+			}
+
+			for ; run > 0 && err == nil; run-- {
+				inputs = append(inputs, state)
+			}
+		}
+
+		r.inputs = append(r.inputs, inputs)
+	}
+
+	if err != nil {
+		return r, fmt.Errorf("%s: corrupted replay: %w", path, err)
+	}
+
+	return r, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// testReplayOf returns a valid replay of the given game mode, with the given input for every player.
+func testReplayOf(mode gameMode, inputs ...[]inputState) replay {
+	r := replay{
+		seed:            42,
+		mode:            mode,
+		randomizer:      "bag7",
+		lockReset:       "move",
+		gravity:         "naive",
+		pieces:          standardPieceSetName,
+		width:           10,
+		height:          20,
+		previewCount:    3,
+		lockDelayFrames: 30,
+		inputs:          inputs,
+	}
+
+	for range inputs {
+		r.handlings = append(r.handlings, handling{das: 10, arr: 2, sdf: 20})
+	}
+
+	return r
+}
+
+func TestReplayRoundTrip(t *testing.T) {
+	left := inputState{down: keyBit(rl.KeyLeft), pressed: keyBit(rl.KeyLeft)}
+	held := inputState{down: keyBit(rl.KeyLeft)}
+
+	tests := []struct {
+		name string
+		r    replay
+	}{
+		{"no input", testReplayOf(modeMarathon, nil)},
+		{"runs of input", testReplayOf(modeSprint, []inputState{{}, {}, left, held, held, held, {}})},
+		{"versus", testReplayOf(modeVersus, []inputState{left, held}, []inputState{{}, {}, {}})},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "game.rep")
+
+		if err := tt.r.save(path); err != nil {
+			t.Fatalf("%s: save(): %v", tt.name, err)
+		}
+
+		got, err := loadReplay(path)
+		if err != nil {
+			t.Fatalf("%s: loadReplay(): %v", tt.name, err)
+		}
+
+		// The input of a player with no frames is read back as an empty list
+		for k := range got.inputs {
+			if len(got.inputs[k]) == 0 && len(tt.r.inputs[k]) == 0 {
+				got.inputs[k] = tt.r.inputs[k]
+			}
+		}
+
+		if !reflect.DeepEqual(got, tt.r) {
+			t.Errorf("%s: loadReplay() = %+v, want %+v", tt.name, got, tt.r)
+		}
+	}
+}
+
+func TestLoadReplayRejectsCorruptedFiles(t *testing.T) {
+	// savedReplay returns the bytes of the given replay file
+	savedReplay := func(r replay) []byte {
+		path := filepath.Join(t.TempDir(), "game.rep")
+		if err := r.save(path); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return data
+	}
+
+	varint := func(n uint64) []byte {
+		var b [binary.MaxVarintLen64]byte
+		return b[:binary.PutUvarint(b[:], n)]
+	}
+
+	noFrames := savedReplay(testReplayOf(modeMarathon, []inputState{}))
+	oneFrame := savedReplay(testReplayOf(modeMarathon, []inputState{{}}))
+
+	// In both files, the number of frames and the input runs are at the very end
+	head := noFrames[:len(noFrames)-1]
+
+	badSettings := testReplayOf(modeMarathon, nil)
+	badSettings.width = maxBoardWidth + 1
+
+	badHandling := testReplayOf(modeMarathon, nil)
+	badHandling.handlings[0].sdf = 0
+
+	badPuzzle := testReplayOf(modePuzzle, nil)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not a replay", []byte("PNG")},
+		{"unsupported version", append([]byte(replayMagic), varint(replayVersion+1)...)},
+		{"truncated", oneFrame[:len(oneFrame)-2]},
+		{"string longer than the file", bytes.Join([][]byte{[]byte(replayMagic), varint(replayVersion), varint(0), varint(0), varint(1 << 40)}, nil)},
+		{"too many frames", append(append([]byte(nil), head...), varint(maxReplayFrames+1)...)},
+		{"run longer than the replay", append(append([]byte(nil), head...), 1, 2, 0, 0)},
+		{"empty run", append(append([]byte(nil), head...), 1, 0, 0, 0)},
+		{"invalid settings", savedReplay(badSettings)},
+		{"invalid handling", savedReplay(badHandling)},
+		{"puzzle without a puzzle", savedReplay(badPuzzle)},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "game.rep")
+		if err := os.WriteFile(path, tt.data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := loadReplay(path); err == nil {
+			t.Errorf("%s: loadReplay() doesn't fail", tt.name)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ------------------------------------------------------------------------------------
+// Scoring, levels and gravity (Tetris guideline)
+// ------------------------------------------------------------------------------------
+
+const (
+	linesPerLevel  = 10 // Number of lines to clear to reach the next level
+	softDropPoints = 1  // Points per cell moved down with a soft drop
+	hardDropPoints = 2  // Points per cell moved down with a hard drop
+	comboPoints    = 50 // Points per combo step, multiplied by the level
+)
+
+// lineClearPoints are the points awarded for clearing 1, 2, 3 or 4 (tetris) lines at once, multiplied by the level.
+// NOTE: Bigger pieces (see pieces.go) can clear more lines at once, which scores like a tetris.
+var lineClearPoints = [...]int{0, 100, 300, 500, 800}
+
+// gravityTable holds the number of frames the piece waits before moving down one cell, for every level
+// (the first entry is level 1). It follows the guideline curve: (0.8 - (level-1) * 0.007)^(level-1) seconds per row.
+// NOTE: Levels beyond the end of the table keep the last (fastest) value.
+var gravityTable = [...]int{60, 48, 37, 28, 21, 16, 11, 8, 6, 4, 3, 2, 1, 1, 1}
+
+// dropInterval returns the number of frames to wait before moving the piece down one cell at the current level.
+func dropInterval() int {
+	if level > len(gravityTable) {
+		return gravityTable[len(gravityTable)-1]
+	}
+
+	return gravityTable[level-1]
+}
+
+// isDifficultClear reports whether clearing that many lines at once is a "difficult" clear,
+// which keeps the back-to-back chain going: a tetris, or any T-spin that clears lines.
+func isDifficultClear(clearedLines int, spin tSpin) bool {
+	return clearedLines >= 4 || (spin != tSpinNone && clearedLines > 0)
+}
+
+// scoreLock awards the points for a piece that has just been locked, clearing the given number of lines
+// (maybe with a T-spin), and updates the combo and back-to-back counters.
+// NOTE: The lines and the level are counted by countClearedLines.
+func scoreLock(clearedLines int, spin tSpin) {
+	points := lineClearPoints[len(lineClearPoints)-1] * level
+	if clearedLines < len(lineClearPoints) {
+		points = lineClearPoints[clearedLines] * level
+	}
+
+	if spin != tSpinNone {
+		points = tSpinPoints[spin][clearedLines] * level
+
+		spinLabel = tSpinName(spin, clearedLines)
+		spinLabelCounter = timeToShowSpinLabel
+	}
+
+	if clearedLines == 0 {
+		score += points
+		combo = -1 // A piece that doesn't clear any line breaks the combo, but not the back-to-back chain
+		return
+	}
+
+	// Back-to-back: consecutive difficult clears are worth 50% more
+	if isDifficultClear(clearedLines, spin) {
+		if isBackToBack {
+			points += points / 2
+		}
+
+		isBackToBack = true
+	} else {
+		isBackToBack = false
+	}
+
+	// Combo: every consecutive piece that clears lines adds a bonus
+	combo++
+	points += comboPoints * combo * level
+
+	score += points
+}
+
+// scoreChain awards the points for the lines completed by the squares falling after a line clear
+// (see cleargravity.go). Every step of the chain multiplies the line clear points.
+func scoreChain(clearedLines int) {
+	points := lineClearPoints[len(lineClearPoints)-1]
+	if clearedLines < len(lineClearPoints) {
+		points = lineClearPoints[clearedLines]
+	}
+
+	score += points * level * (chain + 1)
+}
+
+// drawStatistics draws the score, lines and level, plus the active combo and back-to-back chain.
+func drawStatistics(posX, posY int32) {
+	DrawText(fmt.Sprintf("SCORE: %06d", score), posX, posY, 20, rl.Gray)
+	DrawText(fmt.Sprintf("LINES: %04d", lines), posX, posY+30, 20, rl.Gray)
+	DrawText(fmt.Sprintf("LEVEL: %02d", level), posX, posY+60, 20, rl.Gray)
+
+	if combo > 0 {
+		DrawText(fmt.Sprintf("COMBO x%d", combo), posX, posY+90, 10, rl.Maroon)
+	}
+
+	if isBackToBack {
+		DrawText("BACK-TO-BACK", posX, posY+105, 10, rl.Maroon)
+	}
+
+	if chain > 0 {
+		DrawText(fmt.Sprintf("CHAIN x%d", chain+1), posX, posY+120, 10, rl.Maroon)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import "testing"
+
+func TestScoreLock(t *testing.T) {
+	tests := []struct {
+		name         string
+		clearedLines int
+		spin         tSpin
+		level        int
+		combo        int
+		isBackToBack bool
+
+		wantScore        int
+		wantCombo        int
+		wantIsBackToBack bool
+	}{
+		{"no line breaks the combo", 0, tSpinNone, 1, 3, true, 0, -1, true},
+		{"single", 1, tSpinNone, 1, -1, false, 100, 0, false},
+		{"double in a combo", 2, tSpinNone, 1, 1, false, 300 + 2*50, 2, false},
+		{"triple at level 3", 3, tSpinNone, 3, -1, false, 500 * 3, 0, false},
+		{"tetris", 4, tSpinNone, 1, -1, false, 800, 0, true},
+		{"back-to-back tetris", 4, tSpinNone, 2, -1, true, 800 * 2 * 3 / 2, 0, true},
+		{"single breaks the back-to-back", 1, tSpinNone, 1, -1, true, 100, 0, false},
+		{"more than 4 lines scores as a tetris", 5, tSpinNone, 1, -1, false, 800, 0, true},
+		{"T-spin with no line", 0, tSpinFull, 1, -1, false, 400, -1, false},
+		{"T-spin mini single", 1, tSpinMini, 1, -1, false, 200, 0, true},
+		{"T-spin double", 2, tSpinFull, 2, -1, false, 1200 * 2, 0, true},
+		{"back-to-back T-spin triple", 3, tSpinFull, 1, -1, true, 1600 * 3 / 2, 0, true},
+	}
+
+	for _, tt := range tests {
+		score, level, combo, isBackToBack = 0, tt.level, tt.combo, tt.isBackToBack
+
+		scoreLock(tt.clearedLines, tt.spin)
+
+		if score != tt.wantScore || combo != tt.wantCombo || isBackToBack != tt.wantIsBackToBack {
+			t.Errorf("%s: score %d, combo %d, back-to-back %v, want %d, %d, %v",
+				tt.name, score, combo, isBackToBack, tt.wantScore, tt.wantCombo, tt.wantIsBackToBack)
+		}
+	}
+}
+
+func TestScoreChain(t *testing.T) {
+	tests := []struct {
+		clearedLines int
+		level        int
+		chain        int
+		want         int
+	}{
+		{1, 1, 1, 100 * 2},
+		{2, 3, 1, 300 * 3 * 2},
+		{1, 1, 3, 100 * 4},
+		{6, 1, 1, 800 * 2},
+	}
+
+	for _, tt := range tests {
+		score, level, chain = 0, tt.level, tt.chain
+
+		scoreChain(tt.clearedLines)
+
+		if score != tt.want {
+			t.Errorf("scoreChain(%d) at level %d, chain %d: score %d, want %d", tt.clearedLines, tt.level, tt.chain, score, tt.want)
+		}
+	}
+
+	chain = 0
+}
+
+func TestDropInterval(t *testing.T) {
+	tests := []struct {
+		level int
+		want  int
+	}{
+		{1, 60},
+		{10, 4},
+		{15, 1},
+		{30, 1},
+	}
+
+	for _, tt := range tests {
+		level = tt.level
+		if got := dropInterval(); got != tt.want {
+			t.Errorf("dropInterval() at level %d = %d, want %d", tt.level, got, tt.want)
+		}
+	}
+
+	level = 1
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ------------------------------------------------------------------------------------
+// Skins
+// ------------------------------------------------------------------------------------
+
+// skin holds the colours used to draw the game.
+// NOTE: With a custom piece set (see pieces.go) the pieces keep the colours of their data file,
+//
+//	unless the skin is monochrome.
+type skin struct {
+	name         string
+	isMonochrome bool                       // does the skin draw all the pieces the same colour?
+	pieces       [tetrominoCount]color.RGBA // Colour of the squares of every tetromino, once locked (FULL).
+	moving       [tetrominoCount]color.RGBA // Colour of the squares of every tetromino, while it's the active piece (MOVING).
+	background   color.RGBA
+	gridLines    color.RGBA // Outline (top and left sides) of the EMPTY squares.
+	gridShadow   color.RGBA // Outline (bottom and right sides) of the EMPTY squares.
+	wall         color.RGBA // Colour of the BLOCK squares.
+	ghost        color.RGBA // Outline of the ghost piece.
+	flash        color.RGBA // Colour the completed lines flash with before being deleted (FADING).
+	garbage      color.RGBA // Colour of the garbage squares sent by the opponent in versus mode.
+}
+
+// guidelineColors are the standard tetromino colours, as found in most modern Tetris games.
+var guidelineColors = [tetrominoCount]color.RGBA{
+	tetrominoO: rl.Gold,
+	tetrominoL: rl.Orange,
+	tetrominoJ: rl.Blue,
+	tetrominoI: rl.SkyBlue,
+	tetrominoT: rl.Purple,
+	tetrominoZ: rl.Red,
+	tetrominoS: rl.Lime,
+}
+
+// contrastColors are bright colours that stand out on a black background.
+var contrastColors = [tetrominoCount]color.RGBA{
+	tetrominoO: rl.Yellow,
+	tetrominoL: rl.Orange,
+	tetrominoJ: rl.NewColor(80, 140, 255, 255),
+	tetrominoI: rl.NewColor(0, 255, 255, 255),
+	tetrominoT: rl.Magenta,
+	tetrominoZ: rl.Red,
+	tetrominoS: rl.Green,
+}
+
+// skins lists the available skins, in the order the player cycles through them with [TAB].
+var skins = []skin{
+	{
+		name:       "guideline",
+		pieces:     guidelineColors,
+		moving:     guidelineColors,
+		background: rl.RayWhite,
+		gridLines:  rl.LightGray,
+		gridShadow: rl.DarkGray,
+		wall:       rl.LightGray,
+		ghost:      rl.DarkGray,
+		flash:      rl.White,
+		garbage:    rl.Gray,
+	},
+	{
+		name:         "monochrome",
+		isMonochrome: true,
+		pieces:       sameColor(rl.Gray),
+		moving:       sameColor(rl.DarkGray),
+		background:   rl.RayWhite,
+		gridLines:    rl.LightGray,
+		gridShadow:   rl.DarkGray,
+		wall:         rl.LightGray,
+		ghost:        rl.DarkGray,
+		flash:        rl.Maroon,
+		garbage:      rl.DarkGray,
+	},
+	{
+		name:       "contrast",
+		pieces:     contrastColors,
+		moving:     contrastColors,
+		background: rl.Black,
+		gridLines:  rl.DarkGray,
+		gridShadow: rl.DarkGray,
+		wall:       rl.White,
+		ghost:      rl.White,
+		flash:      rl.White,
+		garbage:    rl.Gray,
+	},
+}
+
+// sameColor returns the colour of every tetromino for a skin that doesn't tell them apart.
+func sameColor(col color.RGBA) [tetrominoCount]color.RGBA {
+	var colors [tetrominoCount]color.RGBA
+	for kind := range colors {
+		colors[kind] = col
+	}
+
+	return colors
+}
+
+// lockedColor returns the colour of a locked square coming from the given tetromino (or from garbage).
+func (s skin) lockedColor(kind tetromino) color.RGBA {
+	switch {
+	case kind == garbageKind:
+		return s.garbage
+	case isStandardPieceSet:
+		return s.pieces[kind]
+	case s.isMonochrome:
+		return s.pieces[0]
+	}
+
+	return pieceSet[kind].color
+}
+
+// movingColor returns the colour of the squares of the given tetromino, while it's the active piece.
+func (s skin) movingColor(kind tetromino) color.RGBA {
+	switch {
+	case isStandardPieceSet:
+		return s.moving[kind]
+	case s.isMonochrome:
+		return s.moving[0]
+	}
+
+	return pieceSet[kind].color
+}
+
+// findSkin returns the index in skins of the skin with the given name.
+func findSkin(name string) (int, error) {
+	names := make([]string, len(skins))
+	for k, s := range skins {
+		if s.name == name {
+			return k, nil
+		}
+
+		names[k] = s.name
+	}
+
+	return 0, fmt.Errorf("unknown skin %q (valid: %s)", name, strings.Join(names, ", "))
+}
+
+// currentSkin returns the skin in use.
+func currentSkin() skin {
+	return skins[skinIndex]
+}
+
+// nextSkin switches to the next skin in the list.
+func nextSkin() {
+	skinIndex = (skinIndex + 1) % len(skins)
+}
@@ -0,0 +1,247 @@
+package main
+
+// ------------------------------------------------------------------------------------
+// Super Rotation System (SRS)
+// ------------------------------------------------------------------------------------
+//
+// Every tetromino lives inside a square bounding box (3x3 for J, L, S, T and Z, 4x4 for I
+// and 2x2 for O) and has four rotation states that are obtained by rotating the spawn state
+// around the centre of that box:
+//
+//	rotation0 (spawn) --CW--> rotationR --CW--> rotation2 --CW--> rotationL --CW--> rotation0
+//
+// When the rotated piece does not fit where it is, SRS tries to "kick" it to a few alternative
+// positions (the kick tables below) before giving up, which is what lets a piece rotate next to
+// a wall or tucked under another piece.
+//
+// The pieces of a custom set (see pieces.go) rotate the same way around the centre of their own bounding box,
+// but they are only kicked to a few simple positions.
+
+type tetromino int
+
+const (
+	tetrominoO tetromino = iota
+	tetrominoL
+	tetrominoJ
+	tetrominoI
+	tetrominoT
+	tetrominoZ
+	tetrominoS
+	tetrominoCount
+
+	noTetromino tetromino = -1 // Not a piece, e.g. the initial history of the TGM randomizer with a custom piece set
+)
+
+type rotationState int
+
+const (
+	rotation0 rotationState = iota // Spawn state
+	rotationR                      // One clockwise turn from spawn
+	rotation2                      // Two turns from spawn (upside down)
+	rotationL                      // One counter-clockwise turn from spawn
+)
+
+// rotationDirection is the number of clockwise quarter turns to apply.
+type rotationDirection int
+
+const (
+	rotateClockwise        rotationDirection = 1
+	rotate180              rotationDirection = 2
+	rotateCounterClockwise rotationDirection = 3
+)
+
+// cell is a (x, y) coordinate in grid squares, where y grows downwards like the grid does.
+type cell struct {
+	x, y int
+}
+
+// tetrominoSpawn is the standard piece set (see pieces.go): the spawn state (rotation0) of every tetromino,
+// and the size of the bounding box it rotates in.
+var tetrominoSpawn = [tetrominoCount]pieceDef{
+	tetrominoO: {name: "O", size: 2, cells: []cell{{0, 0}, {1, 0}, {0, 1}, {1, 1}}},
+	tetrominoL: {name: "L", size: 3, cells: []cell{{2, 0}, {0, 1}, {1, 1}, {2, 1}}},
+	tetrominoJ: {name: "J", size: 3, cells: []cell{{0, 0}, {0, 1}, {1, 1}, {2, 1}}},
+	tetrominoI: {name: "I", size: 4, cells: []cell{{0, 1}, {1, 1}, {2, 1}, {3, 1}}},
+	tetrominoT: {name: "T", size: 3, cells: []cell{{1, 0}, {0, 1}, {1, 1}, {2, 1}}},
+	tetrominoZ: {name: "Z", size: 3, cells: []cell{{0, 0}, {1, 0}, {1, 1}, {2, 1}}},
+	tetrominoS: {name: "S", size: 3, cells: []cell{{1, 0}, {2, 0}, {0, 1}, {1, 1}}},
+}
+
+// Kick tables, as published in the Tetris guideline. Offsets are written the way the guideline
+// does (y grows upwards), so they are flipped when applied to the grid (see kickOffsets).
+// The first test is always (0, 0), that is, the plain rotation.
+var (
+	kicksJLSTZ = map[[2]rotationState][5]cell{
+		{rotation0, rotationR}: {{0, 0}, {-1, 0}, {-1, +1}, {0, -2}, {-1, -2}},
+		{rotationR, rotation0}: {{0, 0}, {+1, 0}, {+1, -1}, {0, +2}, {+1, +2}},
+		{rotationR, rotation2}: {{0, 0}, {+1, 0}, {+1, -1}, {0, +2}, {+1, +2}},
+		{rotation2, rotationR}: {{0, 0}, {-1, 0}, {-1, +1}, {0, -2}, {-1, -2}},
+		{rotation2, rotationL}: {{0, 0}, {+1, 0}, {+1, +1}, {0, -2}, {+1, -2}},
+		{rotationL, rotation2}: {{0, 0}, {-1, 0}, {-1, -1}, {0, +2}, {-1, +2}},
+		{rotationL, rotation0}: {{0, 0}, {-1, 0}, {-1, -1}, {0, +2}, {-1, +2}},
+		{rotation0, rotationL}: {{0, 0}, {+1, 0}, {+1, +1}, {0, -2}, {+1, -2}},
+	}
+
+	kicksI = map[[2]rotationState][5]cell{
+		{rotation0, rotationR}: {{0, 0}, {-2, 0}, {+1, 0}, {-2, -1}, {+1, +2}},
+		{rotationR, rotation0}: {{0, 0}, {+2, 0}, {-1, 0}, {+2, +1}, {-1, -2}},
+		{rotationR, rotation2}: {{0, 0}, {-1, 0}, {+2, 0}, {-1, +2}, {+2, -1}},
+		{rotation2, rotationR}: {{0, 0}, {+1, 0}, {-2, 0}, {+1, -2}, {-2, +1}},
+		{rotation2, rotationL}: {{0, 0}, {+2, 0}, {-1, 0}, {+2, +1}, {-1, -2}},
+		{rotationL, rotation2}: {{0, 0}, {-2, 0}, {+1, 0}, {-2, -1}, {+1, +2}},
+		{rotationL, rotation0}: {{0, 0}, {+1, 0}, {-2, 0}, {+1, -2}, {-2, +1}},
+		{rotation0, rotationL}: {{0, 0}, {-1, 0}, {+2, 0}, {-1, +2}, {+2, -1}},
+	}
+
+	// The guideline does not define 180° rotations, so we use the widely adopted SRS+ table.
+	kicks180 = map[[2]rotationState][6]cell{
+		{rotation0, rotation2}: {{0, 0}, {0, +1}, {+1, +1}, {-1, +1}, {+1, 0}, {-1, 0}},
+		{rotationR, rotationL}: {{0, 0}, {+1, 0}, {+1, +2}, {+1, +1}, {0, +2}, {0, +1}},
+		{rotation2, rotation0}: {{0, 0}, {0, -1}, {-1, -1}, {+1, -1}, {-1, 0}, {+1, 0}},
+		{rotationL, rotationR}: {{0, 0}, {-1, 0}, {-1, +2}, {-1, +1}, {0, +2}, {0, +1}},
+	}
+)
+
+// rotate returns the rotation state reached after turning in the given direction.
+func (r rotationState) rotate(direction rotationDirection) rotationState {
+	return (r + rotationState(direction)) % 4
+}
+
+// tetrominoCells returns the squares occupied by the tetromino in the given rotation state,
+// relative to the top-left corner of its bounding box.
+func tetrominoCells(kind tetromino, rotation rotationState) []cell {
+	spawn := pieceSet[kind]
+	cells := append([]cell(nil), spawn.cells...)
+
+	// Rotate clockwise around the centre of the bounding box, once per quarter turn.
+	for r := rotation0; r < rotation; r++ {
+		for k, c := range cells {
+			cells[k] = cell{x: spawn.size - 1 - c.y, y: c.x}
+		}
+	}
+
+	return cells
+}
+
+// tetrominoShape returns the matrix (MOVING squares) of the tetromino in the given rotation state,
+// as big as its bounding box.
+func tetrominoShape(kind tetromino, rotation rotationState) [][]gridSquare {
+	shape := newMatrix[gridSquare](pieceSet[kind].size, pieceSet[kind].size)
+
+	for _, c := range tetrominoCells(kind, rotation) {
+		shape[c.x][c.y] = MOVING
+	}
+
+	return shape
+}
+
+// tetrominoSpawnX returns the X position (in grid squares) where the tetromino's bounding box
+// spawns, so that every piece appears centered in the playable area.
+func tetrominoSpawnX(kind tetromino) int {
+	return 1 + (gridSizeX-2-pieceSet[kind].size)/2
+}
+
+// kickOffsets returns the list of offsets (in grid coordinates) to try, in order, when turning
+// the tetromino from one rotation state to another.
+func kickOffsets(kind tetromino, from, to rotationState) []cell {
+	var tests []cell
+
+	switch {
+	case !isStandardPieceSet:
+		return simpleKicks // Already in grid coordinates
+	case kind == tetrominoO:
+		tests = []cell{{0, 0}} // The O piece never needs to be kicked
+	case from.rotate(rotate180) == to:
+		t := kicks180[[2]rotationState{from, to}]
+		tests = t[:]
+	case kind == tetrominoI:
+		t := kicksI[[2]rotationState{from, to}]
+		tests = t[:]
+	default:
+		t := kicksJLSTZ[[2]rotationState{from, to}]
+		tests = t[:]
+	}
+
+	// The guideline tables have the Y axis pointing up, while our grid has it pointing down.
+	offsets := make([]cell, len(tests))
+	for k, t := range tests {
+		offsets[k] = cell{x: t.x, y: -t.y}
+	}
+
+	return offsets
+}
+
+// pieceFits reports whether the given shape can be placed with its top-left corner at (posX, posY),
+// that is, every square of the shape lands inside the grid on an EMPTY (or currently MOVING) square.
+func pieceFits(shape [][]gridSquare, posX, posY int) bool {
+	for i := range shape {
+		for j := range shape[i] {
+			if shape[i][j] != MOVING {
+				continue
+			}
+
+			x, y := posX+i, posY+j
+			if x < 0 || x >= gridSizeX || y < 0 || y >= gridSizeY {
+				return false
+			}
+
+			if grid[x][y] != EMPTY && grid[x][y] != MOVING {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// rotatePiece turns the active piece following the SRS rules.
+// It returns false when none of the kick tests found room for the rotated piece.
+func rotatePiece(direction rotationDirection) bool {
+	to := pieceRotation.rotate(direction)
+	shape := tetrominoShape(pieceKind, to)
+
+	for k, kick := range kickOffsets(pieceKind, pieceRotation, to) {
+		if !pieceFits(shape, piecePosX+kick.x, piecePosY+kick.y) {
+			continue
+		}
+
+		clearMovingSquares()
+
+		piece = shape
+		pieceRotation = to
+		piecePosX += kick.x
+		piecePosY += kick.y
+
+		placePiece()
+
+		isLastMoveRotation = true
+		lastKickIndex = k
+		isLastRotation180 = direction == rotate180
+
+		return true
+	}
+
+	return false
+}
+
+// clearMovingSquares removes the active piece from the grid.
+func clearMovingSquares() {
+	for j := gridSizeY - 2; j >= 0; j-- {
+		for i := 1; i < gridSizeX-1; i++ {
+			if grid[i][j] == MOVING {
+				grid[i][j] = EMPTY
+			}
+		}
+	}
+}
+
+// placePiece stamps the active piece into the grid as MOVING squares.
+func placePiece() {
+	for i := range piece {
+		for j := range piece[i] {
+			if piece[i][j] == MOVING {
+				grid[piecePosX+i][piecePosY+j] = MOVING
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRotate(t *testing.T) {
+	tests := []struct {
+		from      rotationState
+		direction rotationDirection
+		want      rotationState
+	}{
+		{rotation0, rotateClockwise, rotationR},
+		{rotationR, rotateClockwise, rotation2},
+		{rotationL, rotateClockwise, rotation0},
+		{rotation0, rotateCounterClockwise, rotationL},
+		{rotation0, rotate180, rotation2},
+		{rotationR, rotate180, rotationL},
+	}
+
+	for _, tt := range tests {
+		if got := tt.from.rotate(tt.direction); got != tt.want {
+			t.Errorf("%d.rotate(%d) = %d, want %d", tt.from, tt.direction, got, tt.want)
+		}
+	}
+}
+
+func TestTetrominoCells(t *testing.T) {
+	tests := []struct {
+		kind     tetromino
+		rotation rotationState
+		want     []cell
+	}{
+		{tetrominoT, rotation0, []cell{{1, 0}, {0, 1}, {1, 1}, {2, 1}}},
+		{tetrominoT, rotationR, []cell{{2, 1}, {1, 0}, {1, 1}, {1, 2}}},
+		{tetrominoT, rotation2, []cell{{1, 2}, {2, 1}, {1, 1}, {0, 1}}},
+		{tetrominoT, rotationL, []cell{{0, 1}, {1, 2}, {1, 1}, {1, 0}}},
+		{tetrominoI, rotationR, []cell{{2, 0}, {2, 1}, {2, 2}, {2, 3}}},
+		{tetrominoO, rotationR, []cell{{1, 0}, {1, 1}, {0, 0}, {0, 1}}},
+	}
+
+	for _, tt := range tests {
+		if got := tetrominoCells(tt.kind, tt.rotation); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tetrominoCells(%s, %d) = %v, want %v", tetrominoSpawn[tt.kind].name, tt.rotation, got, tt.want)
+		}
+	}
+}
+
+func TestKickOffsets(t *testing.T) {
+	tests := []struct {
+		name     string
+		kind     tetromino
+		from, to rotationState
+		want     []cell
+	}{
+		// The offsets of the guideline tables, with the Y axis flipped
+		{"T 0->R", tetrominoT, rotation0, rotationR, []cell{{0, 0}, {-1, 0}, {-1, -1}, {0, 2}, {-1, 2}}},
+		{"T R->0", tetrominoT, rotationR, rotation0, []cell{{0, 0}, {1, 0}, {1, 1}, {0, -2}, {1, -2}}},
+		{"I 0->R", tetrominoI, rotation0, rotationR, []cell{{0, 0}, {-2, 0}, {1, 0}, {-2, 1}, {1, -2}}},
+		{"O 0->R", tetrominoO, rotation0, rotationR, []cell{{0, 0}}},
+		{"T 0->2", tetrominoT, rotation0, rotation2, []cell{{0, 0}, {0, -1}, {1, -1}, {-1, -1}, {1, 0}, {-1, 0}}},
+		{"I R->L", tetrominoI, rotationR, rotationL, []cell{{0, 0}, {1, 0}, {1, -2}, {1, -1}, {0, -2}, {0, -1}}},
+	}
+
+	for _, tt := range tests {
+		if got := kickOffsets(tt.kind, tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: kickOffsets() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Turning back undoes the kick: every test of a 90° rotation is the opposite of the same test the other way round.
+func TestKickTablesAreSymmetric(t *testing.T) {
+	for _, table := range []map[[2]rotationState][5]cell{kicksJLSTZ, kicksI} {
+		for turn, tests := range table {
+			back := table[[2]rotationState{turn[1], turn[0]}]
+
+			for k := range tests {
+				if tests[k].x != -back[k].x || tests[k].y != -back[k].y {
+					t.Errorf("%v test %d: %v is not the opposite of %v", turn, k, tests[k], back[k])
+				}
+			}
+		}
+	}
+
+	for turn, tests := range kicks180 {
+		if tests[0] != (cell{}) {
+			t.Errorf("%v: the first 180° test is %v, want the plain rotation", turn, tests[0])
+		}
+	}
+}
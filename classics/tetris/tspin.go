@@ -0,0 +1,114 @@
+package main
+
+import rl "github.com/gen2brain/raylib-go/raylib"
+
+// ------------------------------------------------------------------------------------
+// T-spins
+// ------------------------------------------------------------------------------------
+//
+// A T-spin is a T piece locked right after being rotated into a tight spot. We use the guideline 3-corner rule:
+// at least three of the four squares diagonally adjacent to the center of the T must be occupied (by a wall or
+// another piece). It is a full T-spin when both corners the T is pointing at are occupied, and a T-spin mini
+// otherwise, unless the rotation needed the last (1x2) SRS kick, which always makes it a full T-spin.
+
+const timeToShowSpinLabel = 90 // Number of frames the T-spin label is shown after the piece is locked
+
+type tSpin int
+
+const (
+	tSpinNone tSpin = iota
+	tSpinMini
+	tSpinFull
+)
+
+// tSpinPoints are the points for a T-spin mini and a full T-spin that clear 0, 1, 2 or 3 lines,
+// multiplied by the level. They replace the regular line clear points.
+var tSpinPoints = [...][4]int{
+	tSpinMini: {100, 200, 400, 400},
+	tSpinFull: {400, 800, 1200, 1600},
+}
+
+// tSpinFrontCorners are the two corners (relative to the center of the T) the T is pointing at, per rotation state.
+var tSpinFrontCorners = [4][2]cell{
+	rotation0: {{-1, -1}, {+1, -1}},
+	rotationR: {{+1, -1}, {+1, +1}},
+	rotation2: {{-1, +1}, {+1, +1}},
+	rotationL: {{-1, -1}, {-1, +1}},
+}
+
+// isCornerOccupied reports whether the square at (x, y) is a wall or part of a locked piece.
+func isCornerOccupied(x, y int) bool {
+	if x < 0 || x >= gridSizeX || y < 0 || y >= gridSizeY {
+		return true
+	}
+
+	return grid[x][y] != EMPTY && grid[x][y] != MOVING
+}
+
+// detectTSpin checks if the active piece, about to be locked, makes a T-spin.
+func detectTSpin() tSpin {
+	if !isStandardPieceSet || pieceKind != tetrominoT || !isLastMoveRotation {
+		return tSpinNone
+	}
+
+	// The center of the T is always the center of its 3x3 bounding box
+	centerX, centerY := piecePosX+1, piecePosY+1
+
+	corners := 0
+	for _, c := range [4]cell{{-1, -1}, {+1, -1}, {-1, +1}, {+1, +1}} {
+		if isCornerOccupied(centerX+c.x, centerY+c.y) {
+			corners++
+		}
+	}
+
+	if corners < 3 {
+		return tSpinNone
+	}
+
+	front := 0
+	for _, c := range tSpinFrontCorners[pieceRotation] {
+		if isCornerOccupied(centerX+c.x, centerY+c.y) {
+			front++
+		}
+	}
+
+	// The last kick test of a 90° rotation (see kicksJLSTZ) always makes a full T-spin
+	if front == 2 || (lastKickIndex == 4 && !isLastRotation180) {
+		return tSpinFull
+	}
+
+	return tSpinMini
+}
+
+// tSpinName returns the label shown to the player, e.g. "T-SPIN DOUBLE".
+func tSpinName(spin tSpin, clearedLines int) string {
+	name := "T-SPIN"
+	if spin == tSpinMini {
+		name = "T-SPIN MINI"
+	}
+
+	switch clearedLines {
+	case 1:
+		name += " SINGLE"
+	case 2:
+		name += " DOUBLE"
+	case 3:
+		name += " TRIPLE"
+	}
+
+	return name
+}
+
+// drawSpinLabel flashes the name of the last T-spin, the same way the completed lines flash before being deleted.
+func drawSpinLabel(posX, posY int32) {
+	if spinLabelCounter <= 0 {
+		return
+	}
+
+	col := rl.Gray
+	if spinLabelCounter%8 < 4 {
+		col = rl.Maroon
+	}
+
+	DrawText(spinLabel, posX, posY, 10, col)
+}
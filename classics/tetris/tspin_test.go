@@ -0,0 +1,83 @@
+package main
+
+import "testing"
+
+func TestDetectTSpin(t *testing.T) {
+	// The T is locked with its bounding box at (4, 10): its center is (5, 11), and its corners are these squares
+	var (
+		topLeft     = cell{4, 10}
+		topRight    = cell{6, 10}
+		bottomLeft  = cell{4, 12}
+		bottomRight = cell{6, 12}
+	)
+
+	tests := []struct {
+		name       string
+		kind       tetromino
+		rotation   rotationState
+		isRotation bool
+		kickIndex  int
+		is180      bool
+		occupied   []cell
+		want       tSpin
+	}{
+		{"not a T", tetrominoL, rotation0, true, 0, false, []cell{topLeft, topRight, bottomLeft}, tSpinNone},
+		{"moved last", tetrominoT, rotation0, false, 0, false, []cell{topLeft, topRight, bottomLeft}, tSpinNone},
+		{"two corners", tetrominoT, rotation0, true, 0, false, []cell{topLeft, topRight}, tSpinNone},
+		{"both front corners", tetrominoT, rotation0, true, 0, false, []cell{topLeft, topRight, bottomLeft}, tSpinFull},
+		{"four corners", tetrominoT, rotation2, true, 0, false, []cell{topLeft, topRight, bottomLeft, bottomRight}, tSpinFull},
+		{"one front corner", tetrominoT, rotation0, true, 0, false, []cell{topLeft, bottomLeft, bottomRight}, tSpinMini},
+		{"pointing right", tetrominoT, rotationR, true, 0, false, []cell{topRight, bottomRight, bottomLeft}, tSpinFull},
+		{"pointing left, one front corner", tetrominoT, rotationL, true, 0, false, []cell{topLeft, topRight, bottomRight}, tSpinMini},
+		{"last 90° kick", tetrominoT, rotation0, true, 4, false, []cell{topLeft, bottomLeft, bottomRight}, tSpinFull},
+		{"kick 4 of a 180° rotation", tetrominoT, rotation0, true, 4, true, []cell{topLeft, bottomLeft, bottomRight}, tSpinMini},
+	}
+
+	for _, tt := range tests {
+		grid = newMatrix[gridSquare](gridSizeX, gridSizeY)
+		for _, c := range tt.occupied {
+			grid[c.x][c.y] = FULL
+		}
+
+		pieceKind, pieceRotation = tt.kind, tt.rotation
+		piecePosX, piecePosY = 4, 10
+		isLastMoveRotation, lastKickIndex, isLastRotation180 = tt.isRotation, tt.kickIndex, tt.is180
+
+		if got := detectTSpin(); got != tt.want {
+			t.Errorf("%s: detectTSpin() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// The walls and the floor count as occupied corners.
+func TestDetectTSpinAgainstTheFloor(t *testing.T) {
+	grid = newMatrix[gridSquare](gridSizeX, gridSizeY)
+
+	// The T points left, its center in the bottom left square: only its top right corner is in the grid
+	pieceKind, pieceRotation = tetrominoT, rotationL
+	piecePosX, piecePosY = -1, gridSizeY-2
+	isLastMoveRotation, lastKickIndex, isLastRotation180 = true, 0, false
+
+	if got := detectTSpin(); got != tSpinFull {
+		t.Errorf("detectTSpin() = %d, want a full T-spin", got)
+	}
+}
+
+func TestTSpinName(t *testing.T) {
+	tests := []struct {
+		spin         tSpin
+		clearedLines int
+		want         string
+	}{
+		{tSpinFull, 0, "T-SPIN"},
+		{tSpinFull, 2, "T-SPIN DOUBLE"},
+		{tSpinMini, 1, "T-SPIN MINI SINGLE"},
+		{tSpinFull, 3, "T-SPIN TRIPLE"},
+	}
+
+	for _, tt := range tests {
+		if got := tSpinName(tt.spin, tt.clearedLines); got != tt.want {
+			t.Errorf("tSpinName(%d, %d) = %q, want %q", tt.spin, tt.clearedLines, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"fmt"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ------------------------------------------------------------------------------------
+// Versus mode
+// ------------------------------------------------------------------------------------
+//
+// Two players play side by side, each one on their own board and with their own keys.
+// The game logic works on the global variables, so every frame each board is swapped into them (see swapBoard),
+// updated or drawn exactly like in the single player modes, and swapped out again.
+//
+// Clearing 2, 3 or 4 lines at once sends 1, 2 or 4 garbage rows to the opponent. They wait in the pending garbage
+// meter, next to the opponent's grid, and rise from the bottom as soon as the opponent locks a piece without
+// clearing any line. Clearing lines cancels the pending garbage first. The first player to top out loses.
+
+const (
+	noWinner    = -1            // Value of versusWinner while the game is being played
+	garbageKind = tetromino(-2) // Not a tetromino: marks the garbage squares in gridKind
+)
+
+// garbageLines is the number of garbage rows sent to the opponent for clearing 0, 1, 2, 3 and 4 lines at once.
+var garbageLines = [5]int{0, 0, 1, 2, 4}
+
+// versusPlayers holds the name and the keys of each player.
+// Player 1 plays with [WASD], [Q]/[E] to rotate, [R] to rotate 180°, [SPACE] to hard drop and [LEFT SHIFT] to hold.
+// Player 2 plays with the arrows, [RIGHT CTRL] to rotate, [/] to rotate 180°, [ENTER] to hard drop
+// and [RIGHT SHIFT] to hold.
+// Once the game is over, either player goes back to the mode selection with [BACKSPACE], which nobody plays with.
+// NOTE: The bot (toggled with [B]) takes the place of player 2.
+var versusPlayers = [2]struct {
+	name string
+	keys keyMap
+}{
+	{
+		name: "PLAYER 1 [WASD]",
+		keys: keyMap{
+			rl.KeyLeft:  rl.KeyA,         // Move left
+			rl.KeyRight: rl.KeyD,         // Move right
+			rl.KeyUp:    rl.KeyW,         // Rotate clockwise
+			rl.KeyDown:  rl.KeyS,         // Soft drop
+			rl.KeyZ:     rl.KeyQ,         // Rotate counter-clockwise
+			rl.KeyX:     rl.KeyE,         // Rotate clockwise
+			rl.KeyA:     rl.KeyR,         // Rotate 180°
+			rl.KeySpace: rl.KeySpace,     // Hard drop
+			rl.KeyC:     rl.KeyLeftShift, // Hold
+			rl.KeyP:     rl.KeyP,         // Pause (both boards)
+			rl.KeyEnter: rl.KeyBackspace, // Choose a mode, once the game is over
+		},
+	},
+	{
+		name: "PLAYER 2 [ARROWS]",
+		keys: keyMap{
+			rl.KeyLeft:  rl.KeyLeft,         // Move left
+			rl.KeyRight: rl.KeyRight,        // Move right
+			rl.KeyUp:    rl.KeyUp,           // Rotate clockwise
+			rl.KeyDown:  rl.KeyDown,         // Soft drop
+			rl.KeyZ:     rl.KeyRightControl, // Rotate counter-clockwise
+			rl.KeyA:     rl.KeySlash,        // Rotate 180°
+			rl.KeySpace: rl.KeyEnter,        // Hard drop
+			rl.KeyC:     rl.KeyRightShift,   // Hold
+			rl.KeyP:     rl.KeyP,            // Pause (both boards)
+			rl.KeyEnter: rl.KeyBackspace,    // Choose a mode, once the game is over
+		},
+	},
+}
+
+// boardState holds everything that belongs to the board of one player.
+type boardState struct {
+	isGameover, isPaused, isFirst, isPieceFalling, isDownCollided, hasLineToDelete bool
+
+	gameOverResult gameResult
+	framesCounter  int
+
+	playerHandling handling
+
+	verticalMoveCounter, dasCounter, arrCounter, shiftDirection, turnMovementCounter int
+	fastFallMoveCounter, fadeLineCounter, lockDelayCounter, lockResets, lowestRow    int
+
+	piece                [][]gridSquare
+	piecePosX, piecePosY int
+	pieceKind            tetromino
+	pieceRotation        rotationState
+
+	holdKind           tetromino
+	isHolding, canHold bool
+
+	pieceRandomizer randomizer
+	nextQueue       []tetromino
+	spawnCounter    int
+
+	botTarget                  placement
+	botPlannedPiece, botFrames int
+
+	score, lines, level, combo int
+	isBackToBack               bool
+	chain                      int
+
+	isLastMoveRotation bool
+	lastKickIndex      int
+	isLastRotation180  bool
+	spinLabel          string
+	spinLabelCounter   int
+
+	pendingGarbage, outgoingGarbage, sentGarbage int
+
+	grid            [][]gridSquare
+	gridKind        [][]tetromino
+	gridPiece       [][]int
+	lockedPieces    int
+	isFadingFlashOn bool
+}
+
+// swapBoard exchanges the global variables of the game with the given board.
+// Swapping a board in, running the usual game logic, and swapping it out again plays on that board.
+func swapBoard(b *boardState) {
+	isGameover, b.isGameover = b.isGameover, isGameover
+	isPaused, b.isPaused = b.isPaused, isPaused
+	isFirst, b.isFirst = b.isFirst, isFirst
+	isPieceFalling, b.isPieceFalling = b.isPieceFalling, isPieceFalling
+	isDownCollided, b.isDownCollided = b.isDownCollided, isDownCollided
+	hasLineToDelete, b.hasLineToDelete = b.hasLineToDelete, hasLineToDelete
+
+	gameOverResult, b.gameOverResult = b.gameOverResult, gameOverResult
+	framesCounter, b.framesCounter = b.framesCounter, framesCounter
+
+	playerHandling, b.playerHandling = b.playerHandling, playerHandling
+
+	verticalMoveCounter, b.verticalMoveCounter = b.verticalMoveCounter, verticalMoveCounter
+	dasCounter, b.dasCounter = b.dasCounter, dasCounter
+	arrCounter, b.arrCounter = b.arrCounter, arrCounter
+	shiftDirection, b.shiftDirection = b.shiftDirection, shiftDirection
+	turnMovementCounter, b.turnMovementCounter = b.turnMovementCounter, turnMovementCounter
+	fastFallMoveCounter, b.fastFallMoveCounter = b.fastFallMoveCounter, fastFallMoveCounter
+	fadeLineCounter, b.fadeLineCounter = b.fadeLineCounter, fadeLineCounter
+	lockDelayCounter, b.lockDelayCounter = b.lockDelayCounter, lockDelayCounter
+	lockResets, b.lockResets = b.lockResets, lockResets
+	lowestRow, b.lowestRow = b.lowestRow, lowestRow
+
+	piece, b.piece = b.piece, piece
+	piecePosX, b.piecePosX = b.piecePosX, piecePosX
+	piecePosY, b.piecePosY = b.piecePosY, piecePosY
+	pieceKind, b.pieceKind = b.pieceKind, pieceKind
+	pieceRotation, b.pieceRotation = b.pieceRotation, pieceRotation
+
+	holdKind, b.holdKind = b.holdKind, holdKind
+	isHolding, b.isHolding = b.isHolding, isHolding
+	canHold, b.canHold = b.canHold, canHold
+
+	pieceRandomizer, b.pieceRandomizer = b.pieceRandomizer, pieceRandomizer
+	nextQueue, b.nextQueue = b.nextQueue, nextQueue
+	spawnCounter, b.spawnCounter = b.spawnCounter, spawnCounter
+
+	botTarget, b.botTarget = b.botTarget, botTarget
+	botPlannedPiece, b.botPlannedPiece = b.botPlannedPiece, botPlannedPiece
+	botFrames, b.botFrames = b.botFrames, botFrames
+
+	score, b.score = b.score, score
+	lines, b.lines = b.lines, lines
+	level, b.level = b.level, level
+	combo, b.combo = b.combo, combo
+	isBackToBack, b.isBackToBack = b.isBackToBack, isBackToBack
+	chain, b.chain = b.chain, chain
+
+	isLastMoveRotation, b.isLastMoveRotation = b.isLastMoveRotation, isLastMoveRotation
+	lastKickIndex, b.lastKickIndex = b.lastKickIndex, lastKickIndex
+	isLastRotation180, b.isLastRotation180 = b.isLastRotation180, isLastRotation180
+	spinLabel, b.spinLabel = b.spinLabel, spinLabel
+	spinLabelCounter, b.spinLabelCounter = b.spinLabelCounter, spinLabelCounter
+
+	pendingGarbage, b.pendingGarbage = b.pendingGarbage, pendingGarbage
+	outgoingGarbage, b.outgoingGarbage = b.outgoingGarbage, outgoingGarbage
+	sentGarbage, b.sentGarbage = b.sentGarbage, sentGarbage
+
+	grid, b.grid = b.grid, grid
+	gridKind, b.gridKind = b.gridKind, gridKind
+	gridPiece, b.gridPiece = b.gridPiece, gridPiece
+	lockedPieces, b.lockedPieces = b.lockedPieces, lockedPieces
+	isFadingFlashOn, b.isFadingFlashOn = b.isFadingFlashOn, isFadingFlashOn
+}
+
+// startVersus starts a new game on both boards.
+func startVersus() {
+	for k := range versusBoards {
+		swapBoard(&versusBoards[k])
+		reset()
+		isGameover = false
+		playerHandling = handlings[k] // Every player has their own handling
+		swapBoard(&versusBoards[k])
+	}
+
+	versusWinner = noWinner
+}
+
+// updateVersus updates both boards (one frame), and sends the garbage from one board to the other.
+func updateVersus() {
+	if versusWinner != noWinner {
+		for k := range versusPlayers {
+			if isReplaying {
+				input = replayInput(k)
+			} else {
+				input = pollKeyMap(versusPlayers[k].keys)
+			}
+
+			if isKeyPressed(rl.KeyEnter) {
+				reset()
+				isSelectingMode = true
+				return
+			}
+		}
+
+		return
+	}
+
+	for k := range versusBoards {
+		opponent := 1 - k
+
+		swapBoard(&versusBoards[k])
+
+		switch {
+		case isReplaying:
+			input = replayInput(k)
+		case k == 1 && isBotPlaying:
+			input = botInput()
+		default:
+			input = pollKeyMap(versusPlayers[k].keys)
+		}
+
+		recordInput(k, input)
+
+		UpdateGame()
+
+		garbage, hasToppedOut := outgoingGarbage, isGameover
+		outgoingGarbage = 0
+
+		swapBoard(&versusBoards[k])
+
+		versusBoards[opponent].pendingGarbage += garbage
+
+		if hasToppedOut {
+			versusWinner = opponent
+			return
+		}
+	}
+}
+
+// updateGarbage is called when a piece locks in versus mode.
+// The lines cleared cancel the pending garbage first, and the rest is sent to the opponent.
+// If no line has been cleared, the pending garbage rises from the bottom of the grid instead.
+func updateGarbage(clearedLines int) {
+	if clearedLines > 0 {
+		attack := garbageLines[len(garbageLines)-1] // Bigger pieces can clear more than 4 lines at once
+		if clearedLines < len(garbageLines) {
+			attack = garbageLines[clearedLines]
+		}
+
+		canceled := attack
+		if pendingGarbage < canceled {
+			canceled = pendingGarbage
+		}
+
+		pendingGarbage -= canceled
+		outgoingGarbage += attack - canceled
+		sentGarbage += attack - canceled
+
+		return
+	}
+
+	if pendingGarbage > 0 {
+		addGarbage(pendingGarbage)
+		pendingGarbage = 0
+	}
+}
+
+// addGarbage pushes the stack up by the given number of rows, and fills the rows at the bottom with garbage:
+// full lines except for one hole, in the same random column for all of them.
+func addGarbage(rows int) {
+	playableRows := gridSizeY - 1 // The last row is the floor
+
+	if rows > playableRows {
+		rows = playableRows
+	}
+
+	hole := int(rl.GetRandomValue(1, int32(gridSizeX-2)))
+	hasPushedOut := false
+
+	for i := 1; i < gridSizeX-1; i++ {
+		// Squares pushed above the top of the grid top the player out
+		for j := 0; j < rows; j++ {
+			if grid[i][j] == FULL {
+				hasPushedOut = true
+			}
+		}
+
+		for j := 0; j < playableRows-rows; j++ {
+			grid[i][j] = grid[i][j+rows]
+			gridKind[i][j] = gridKind[i][j+rows]
+			gridPiece[i][j] = gridPiece[i][j+rows]
+		}
+
+		for j := playableRows - rows; j < playableRows; j++ {
+			if i == hole {
+				grid[i][j] = EMPTY
+			} else {
+				grid[i][j] = FULL
+				gridKind[i][j] = garbageKind
+				gridPiece[i][j] = 0
+			}
+		}
+	}
+
+	if hasPushedOut {
+		topOut()
+	}
+}
+
+// drawVersus draws both boards side by side, and the winner once the game is over.
+func drawVersus() {
+	for k := range versusBoards {
+		swapBoard(&versusBoards[k])
+		drawVersusBoard(k)
+		swapBoard(&versusBoards[k])
+	}
+
+	skinName := fmt.Sprintf("SKIN: %s [TAB]", currentSkin().name)
+	DrawText(skinName, screenWidth/2-MeasureText(skinName, 10)/2, 5, 10, rl.Gray)
+
+	if versusWinner != noWinner {
+		title := fmt.Sprintf("PLAYER %d WINS!", versusWinner+1)
+
+		DrawRectangle(0, screenHeight/2-60, screenWidth, 120, rl.Fade(currentSkin().background, 0.9))
+		DrawText(title, screenWidth/2-MeasureText(title, 40)/2, screenHeight/2-45, 40, rl.Maroon)
+
+		const replayMsg = "PRESS [BACKSPACE] TO CHOOSE A MODE"
+		DrawText(replayMsg, screenWidth/2-MeasureText(replayMsg, 20)/2, screenHeight/2+15, 20, rl.Gray)
+	} else if versusBoards[0].isPaused {
+		rl.DrawText("GAME PAUSED", screenWidth/2-rl.MeasureText("GAME PAUSED", 40)/2, screenHeight/2-40, 40, rl.Gray)
+	}
+}
+
+// drawVersusBoard draws the board swapped in, on the half of the screen of the given player.
+func drawVersusBoard(player int) {
+	posX := int32(player) * screenWidth / 2
+	gridEnd := posX + 20 + int32(gridSizeX*squareSize) // Right side of the grid
+	columnX := gridEnd + 25                            // Position of the column at the right of the grid
+
+	drawGrid(rl.Vector2{X: float32(posX + 20), Y: 25})
+	drawGarbageMeter(gridEnd+4, 25)
+
+	// Draw incoming pieces, smaller than in the single player modes to fit both boards on the screen
+	for k, kind := range nextQueue {
+		if k == 0 {
+			drawPreviewBox(tetrominoShape(kind, rotation0), float32(columnX), 40, 15, currentSkin().lockedColor(kind))
+		} else {
+			drawPreviewBox(tetrominoShape(kind, rotation0), float32(columnX), 110+float32(k-1)*45, 10, currentSkin().lockedColor(kind))
+		}
+	}
+
+	DrawText("NEXT:", columnX, 25, 10, rl.Gray)
+
+	// Draw the hold slot, greyed out while it can't be used
+	holdShape := newMatrix[gridSquare](4, 4)
+	holdColor := currentSkin().gridLines
+	if isHolding {
+		holdShape = tetrominoShape(holdKind, rotation0)
+		holdColor = currentSkin().lockedColor(holdKind)
+	}
+
+	if !canHold {
+		holdColor = currentSkin().gridLines
+	}
+
+	drawPreviewBox(holdShape, float32(columnX), 355, 10, holdColor)
+	DrawText("HOLD:", columnX, 340, 10, rl.Gray)
+
+	DrawText(fmt.Sprintf("LINES: %d", lines), columnX, 405, 10, rl.Gray)
+	DrawText(fmt.Sprintf("SENT: %d", sentGarbage), columnX, 420, 10, rl.Gray)
+	drawSpinLabel(columnX, 435)
+
+	name := versusPlayers[player].name
+	if player == 1 && isBotPlaying {
+		name = "BOT [B]"
+	}
+
+	DrawText(name, posX+20, 432, 10, rl.DarkGray)
+}
+
+// drawGarbageMeter draws the pending garbage as a bar rising from the bottom of the playable area of the grid.
+func drawGarbageMeter(posX, posY int32) {
+	const width = 8
+
+	playableRows := gridSizeY - 1 // The last row is the floor
+
+	rows := pendingGarbage
+	if rows > playableRows {
+		rows = playableRows
+	}
+
+	DrawRectangleLines(posX, posY, width, int32(playableRows*squareSize), currentSkin().gridLines)
+	DrawRectangle(posX, posY+int32((playableRows-rows)*squareSize), width, int32(rows*squareSize), rl.Red)
+}